@@ -11,18 +11,85 @@ import (
 )
 
 var (
-	fileName = flag.String("file", "runner.js", "file to run")
-	maxHeap  = flag.Uint("max-heap", 16, "max heap size in MB")
+	fileName      = flag.String("file", "runner.js", "file to run")
+	maxHeap       = flag.Uint("max-heap", 16, "max heap size in MB")
+	heapHeadroom  = flag.Uint("heap-headroom", 16, "heap allowed beyond max-heap to fail a request with out of memory instead of aborting, in MB, 0 disables it")
+	maxCodeSize   = flag.Int("max-code-size", 0, "max code size of a request in bytes, 0 for no limit")
+	maxResultSize = flag.Int("max-result-size", 0, "max result size of a request in bytes, 0 for no limit")
+	maxLogSize    = flag.Int("max-log-size", 0, "max error message size in bytes, 0 for no limit")
+	snapshot      = flag.String("snapshot", "", "snapshot created by the snapshot command, to boot from")
+	scriptCache   = flag.Int("script-cache-size", 128, "number of compiled scripts to cache, 0 to disable")
+	codeCacheDir  = flag.String("code-cache-dir", "", "directory to persist the code cache of the scripts in")
+	deterministic = flag.Bool("deterministic", false, "replace Math.random and the clocks by the seed and the clock of each request")
+	noCodeGen     = flag.Bool("no-code-generation", false, "make eval and new Function throw instead of compiling strings")
+	noWasm        = flag.Bool("no-wasm", false, "remove WebAssembly")
+	noSharedMem   = flag.Bool("no-shared-memory", false, "remove SharedArrayBuffer and Atomics")
+	freeze        = flag.Bool("freeze-intrinsics", false, "freeze the built-in objects")
+	traceLog      = flag.Bool("trace-log", false, "write the trace ID of each request to stderr before running it")
+	polyfills     = flag.Bool("polyfills", false, "define TextEncoder, TextDecoder, atob, btoa, URL, URLSearchParams and structuredClone")
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		createSnapshot(os.Args[2:])
+		return
+	}
+	flag.Parse()
 	fmt.Fprintf(os.Stderr, "v8runner version: %s\n", info.GetVersion())
 	r, err := runner.NewStdioRunner(*fileName, *maxHeap)
 	if err != nil {
 		log.Fatalf("failed to create runner: %s", err)
 	}
+	r.HeapHeadroomMB = *heapHeadroom
+	r.MaxCodeSize = *maxCodeSize
+	r.MaxResultSize = *maxResultSize
+	r.MaxLogSize = *maxLogSize
+	r.Polyfills = *polyfills
+	r.Deterministic = *deterministic
+	if *traceLog {
+		r.TraceLog = os.Stderr
+	}
+	r.Lockdown = runner.LockdownOption{
+		NoCodeGeneration: *noCodeGen,
+		NoWebAssembly:    *noWasm,
+		NoSharedMemory:   *noSharedMem,
+		FreezeIntrinsics: *freeze,
+	}
+	if *scriptCache > 0 {
+		r.ScriptCache = &runner.ScriptCacheOption{Size: *scriptCache, Dir: *codeCacheDir}
+	}
+	if *snapshot != "" {
+		r.Snapshot, err = runner.ReadSnapshotFile(*snapshot)
+		if err != nil {
+			log.Fatalf("failed to read snapshot: %s", err)
+		}
+	}
 	err = r.Process()
 	if err != nil {
 		log.Fatalf("failed to process because: %s", err)
 	}
 }
+
+// createSnapshot implements `v8runner snapshot --bootstrap lib.js -o lib.snap`.
+func createSnapshot(args []string) {
+	cmd := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	bootstrap := cmd.String("bootstrap", "", "script to pre-initialize the runners with")
+	output := cmd.String("o", "", "file to write the snapshot to")
+	_ = cmd.Parse(args)
+	if *bootstrap == "" || *output == "" {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	// #nosec G304 -- the bootstrap script is chosen by the operator
+	source, err := os.ReadFile(*bootstrap)
+	if err != nil {
+		log.Fatalf("failed to read bootstrap: %s", err)
+	}
+	s, err := runner.CreateSnapshot(*bootstrap, string(source))
+	if err != nil {
+		log.Fatalf("failed to create snapshot: %s", err)
+	}
+	if err := s.WriteFile(*output); err != nil {
+		log.Fatalf("failed to write snapshot: %s", err)
+	}
+}
@@ -6,6 +6,11 @@ require (
 	github.com/rs/zerolog v1.31.0
 	github.com/stretchr/testify v1.8.4
 	github.com/stumble/v8go v0.33.1
+	github.com/stumble/v8go/deps/darwin_amd64 v0.0.0-20250618204609-b802926d07ec
+	github.com/stumble/v8go/deps/darwin_arm64 v0.0.0-20250618204609-b802926d07ec
+	github.com/stumble/v8go/deps/linux_amd64 v0.0.0-20250618204609-b802926d07ec
+	github.com/stumble/v8go/deps/linux_arm64 v0.0.0-20250618204609-b802926d07ec
+	golang.org/x/sys v0.12.0
 )
 
 require (
@@ -13,10 +18,5 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stumble/v8go/deps/darwin_amd64 v0.0.0-20250618204609-b802926d07ec // indirect
-	github.com/stumble/v8go/deps/darwin_arm64 v0.0.0-20250618204609-b802926d07ec // indirect
-	github.com/stumble/v8go/deps/linux_amd64 v0.0.0-20250618204609-b802926d07ec // indirect
-	github.com/stumble/v8go/deps/linux_arm64 v0.0.0-20250618204609-b802926d07ec // indirect
-	golang.org/x/sys v0.12.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,36 @@
+package metrics
+
+import "expvar"
+
+// Expvar publishes the metrics as an expvar.Map, served as JSON by the /debug/vars handler
+// of expvar. Histograms are summarized by the variables name_count and name_sum.
+type Expvar struct {
+	vars *expvar.Map
+}
+
+// NewExpvar publishes the metrics under name, and panics if name is already published,
+// like expvar.Publish.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{vars: expvar.NewMap(name)}
+}
+
+// Map returns the published variables.
+func (e *Expvar) Map() *expvar.Map {
+	return e.vars
+}
+
+// IncCounter increments the counter name.
+func (e *Expvar) IncCounter(name string) {
+	e.vars.Add(name, 1)
+}
+
+// AddGauge adds delta to the gauge name.
+func (e *Expvar) AddGauge(name string, delta float64) {
+	e.vars.AddFloat(name, delta)
+}
+
+// Observe records value in the histogram name.
+func (e *Expvar) Observe(name string, value float64) {
+	e.vars.Add(name+"_count", 1)
+	e.vars.AddFloat(name+"_sum", value)
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/stumble/v8runner/pkg/procrunner"
+)
+
+var _ procrunner.Metrics = (*Expvar)(nil)
+
+type ExpvarTestSuite struct {
+	suite.Suite
+}
+
+func TestExpvarTestSuite(t *testing.T) {
+	suite.Run(t, new(ExpvarTestSuite))
+}
+
+func (suite *ExpvarTestSuite) TestPublish() {
+	e := NewExpvar("v8runner_test")
+	e.IncCounter("spawns_total")
+	e.IncCounter("spawns_total")
+	e.AddGauge("in_flight", 1)
+	e.Observe("duration_seconds", 0.25)
+	e.Observe("duration_seconds", 0.5)
+
+	suite.Same(e.Map(), expvar.Get("v8runner_test"))
+	suite.Equal("2", e.Map().Get("spawns_total").String())
+	suite.Equal("1", e.Map().Get("in_flight").String())
+	suite.Equal("2", e.Map().Get("duration_seconds_count").String())
+	suite.Equal("0.75", e.Map().Get("duration_seconds_sum").String())
+	suite.Panics(func() { NewExpvar("v8runner_test") })
+}
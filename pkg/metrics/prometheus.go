@@ -0,0 +1,151 @@
+// Package metrics implements procrunner.Metrics with expvar, and with a registry exposed
+// in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefBuckets are the default upper bounds of the histogram buckets, in seconds.
+var DefBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds counters, gauges and histograms, and serves them in the Prometheus text
+// exposition format. The zero value is not usable, see NewRegistry.
+type Registry struct {
+	mu         sync.Mutex
+	buckets    []float64
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewRegistry creates a registry whose histograms have the upper bounds buckets,
+// in increasing order, or DefBuckets if none.
+func NewRegistry(buckets ...float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	return &Registry{
+		buckets:    buckets,
+		counters:   make(map[string]float64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string]*histogram),
+	}
+}
+
+// IncCounter increments the counter name.
+func (r *Registry) IncCounter(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name]++
+}
+
+// AddGauge adds delta to the gauge name.
+func (r *Registry) AddGauge(name string, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[name] += delta
+}
+
+// Observe records value in the histogram name.
+func (r *Registry) Observe(name string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.histograms[name]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(r.buckets))}
+		r.histograms[name] = h
+	}
+	if i := sort.SearchFloat64s(r.buckets, value); i < len(r.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition format,
+// sorted by name. The metrics are copied first, so that a slow w does not block the
+// metrics being recorded.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	counters, gauges, histograms := r.copy()
+	bw := bufio.NewWriter(w)
+	for _, name := range sortedKeys(counters) {
+		bw.WriteString("# TYPE " + name + " counter\n")
+		bw.WriteString(name + " " + formatFloat(counters[name]) + "\n")
+	}
+	for _, name := range sortedKeys(gauges) {
+		bw.WriteString("# TYPE " + name + " gauge\n")
+		bw.WriteString(name + " " + formatFloat(gauges[name]) + "\n")
+	}
+	for _, name := range sortedKeys(histograms) {
+		h := histograms[name]
+		bw.WriteString("# TYPE " + name + " histogram\n")
+		var cumulative uint64
+		for i, le := range r.buckets {
+			cumulative += h.counts[i]
+			bw.WriteString(name + `_bucket{le="` + formatFloat(le) + `"} ` + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		bw.WriteString(name + `_bucket{le="+Inf"} ` + strconv.FormatUint(h.count, 10) + "\n")
+		bw.WriteString(name + "_sum " + formatFloat(h.sum) + "\n")
+		bw.WriteString(name + "_count " + strconv.FormatUint(h.count, 10) + "\n")
+	}
+	return bw.Flush()
+}
+
+// copy returns a copy of the metrics. The buckets are not copied, they never change.
+func (r *Registry) copy() (map[string]float64, map[string]float64, map[string]histogram) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counters := make(map[string]float64, len(r.counters))
+	for name, v := range r.counters {
+		counters[name] = v
+	}
+	gauges := make(map[string]float64, len(r.gauges))
+	for name, v := range r.gauges {
+		gauges[name] = v
+	}
+	histograms := make(map[string]histogram, len(r.histograms))
+	for name, h := range r.histograms {
+		histograms[name] = histogram{counts: append([]uint64(nil), h.counts...), count: h.count, sum: h.sum}
+	}
+	return counters, gauges, histograms
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WritePrometheus(w)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/stumble/v8runner/pkg/procrunner"
+)
+
+var _ procrunner.Metrics = (*Registry)(nil)
+
+type RegistryTestSuite struct {
+	suite.Suite
+}
+
+func TestRegistryTestSuite(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
+
+func (suite *RegistryTestSuite) TestWritePrometheus() {
+	r := NewRegistry(0.1, 1)
+	r.IncCounter("b_total")
+	r.IncCounter("b_total")
+	r.IncCounter("a_total")
+	r.AddGauge("in_flight", 2)
+	r.AddGauge("in_flight", -0.5)
+	r.Observe("duration_seconds", 0.05)
+	r.Observe("duration_seconds", 0.1)
+	r.Observe("duration_seconds", 0.5)
+	r.Observe("duration_seconds", 3)
+
+	var b strings.Builder
+	suite.Require().NoError(r.WritePrometheus(&b))
+	suite.Equal(`# TYPE a_total counter
+a_total 1
+# TYPE b_total counter
+b_total 2
+# TYPE in_flight gauge
+in_flight 1.5
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 2
+duration_seconds_bucket{le="1"} 3
+duration_seconds_bucket{le="+Inf"} 4
+duration_seconds_sum 3.65
+duration_seconds_count 4
+`, b.String())
+}
+
+func (suite *RegistryTestSuite) TestServeHTTP() {
+	r := NewRegistry()
+	r.IncCounter(procrunner.MetricSpawns)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	suite.Equal(200, w.Code)
+	suite.Equal("text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	suite.Equal("# TYPE v8runner_spawns_total counter\nv8runner_spawns_total 1\n", w.Body.String())
+}
+
+// blockingWriter blocks the writes until unblock is closed.
+type blockingWriter struct {
+	unblock chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.unblock
+	return len(p), nil
+}
+
+func (suite *RegistryTestSuite) TestWriteDoesNotBlockRecording() {
+	r := NewRegistry()
+	r.IncCounter("a_total")
+	w := blockingWriter{unblock: make(chan struct{})}
+	written := make(chan error)
+	go func() {
+		written <- r.WritePrometheus(w)
+	}()
+	recorded := make(chan struct{})
+	go func() {
+		r.IncCounter("a_total")
+		r.Observe("duration_seconds", 1)
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		suite.Fail("recording blocked by a slow writer")
+	}
+	close(w.unblock)
+	suite.NoError(<-written)
+}
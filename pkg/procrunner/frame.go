@@ -0,0 +1,76 @@
+package procrunner
+
+import (
+	"fmt"
+	"io"
+)
+
+// frameLimitReader passes a gob stream through, but fails before a message larger than
+// max() is read, so that the decoder never allocates a buffer for it. 0 means no limit.
+// A gob stream is a sequence of messages, each prefixed by its length as a gob uint:
+// one byte if less than 128, otherwise the negated number of bytes that follow it,
+// followed by the length in big-endian.
+type frameLimitReader struct {
+	r   io.Reader
+	max func() int
+
+	header    [9]byte
+	headerLen int // bytes of header not passed through yet
+	remaining int // bytes of the current message not passed through yet
+}
+
+func newFrameLimitReader(r io.Reader, max func() int) *frameLimitReader {
+	return &frameLimitReader{r: r, max: max}
+}
+
+func (f *frameLimitReader) Read(p []byte) (int, error) {
+	if f.headerLen == 0 && f.remaining == 0 {
+		if err := f.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+	if f.headerLen > 0 {
+		n := copy(p, f.header[len(f.header)-f.headerLen:])
+		f.headerLen -= n
+		return n, nil
+	}
+	if len(p) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err := f.r.Read(p)
+	f.remaining -= n
+	return n, err
+}
+
+// readHeader reads the length of the next message, and keeps it to be passed through
+// at the end of f.header.
+func (f *frameLimitReader) readHeader() error {
+	var first [1]byte
+	if _, err := io.ReadFull(f.r, first[:]); err != nil {
+		return err
+	}
+	length := uint64(first[0])
+	header := first[:]
+	if first[0] >= 0x80 {
+		n := 256 - int(first[0])
+		if n > 8 {
+			return fmt.Errorf("invalid message length prefix: %x", first[0])
+		}
+		buf := make([]byte, 1+n)
+		buf[0] = first[0]
+		if _, err := io.ReadFull(f.r, buf[1:]); err != nil {
+			return err
+		}
+		length = 0
+		for _, b := range buf[1:] {
+			length = length<<8 | uint64(b)
+		}
+		header = buf
+	}
+	if max := f.max(); max > 0 && length > uint64(max) {
+		return fmt.Errorf("%w: message of %d bytes exceeds %d bytes", ErrResultTooLarge, length, max)
+	}
+	f.headerLen = copy(f.header[len(f.header)-len(header):], header)
+	f.remaining = int(length)
+	return nil
+}
@@ -0,0 +1,52 @@
+package procrunner
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/stumble/v8runner/pkg/types"
+)
+
+type FrameLimitReaderTestSuite struct {
+	suite.Suite
+}
+
+func TestFrameLimitReaderTestSuite(t *testing.T) {
+	suite.Run(t, new(FrameLimitReaderTestSuite))
+}
+
+func (suite *FrameLimitReaderTestSuite) TestPassThrough() {
+	buf := &bytes.Buffer{}
+	encoder := gob.NewEncoder(buf)
+	results := []string{"1", strings.Repeat("x", 200), strings.Repeat("y", 70000)}
+	for i, result := range results {
+		suite.NoError(encoder.Encode(types.RunCodeResponse{ID: string(rune('a' + i)), Result: &result}))
+	}
+
+	decoder := gob.NewDecoder(newFrameLimitReader(buf, func() int { return 80000 }))
+	for i, result := range results {
+		var res types.RunCodeResponse
+		suite.Require().NoError(decoder.Decode(&res))
+		suite.Equal(string(rune('a'+i)), res.ID)
+		suite.Equal(result, *res.Result)
+	}
+}
+
+func (suite *FrameLimitReaderTestSuite) TestTooLarge() {
+	buf := &bytes.Buffer{}
+	encoder := gob.NewEncoder(buf)
+	small := "1"
+	large := strings.Repeat("x", 1000)
+	suite.NoError(encoder.Encode(types.RunCodeResponse{ID: "a", Result: &small}))
+	suite.NoError(encoder.Encode(types.RunCodeResponse{ID: "b", Result: &large}))
+
+	decoder := gob.NewDecoder(newFrameLimitReader(buf, func() int { return 500 }))
+	var res types.RunCodeResponse
+	suite.Require().NoError(decoder.Decode(&res))
+	suite.Equal("a", res.ID)
+	err := decoder.Decode(&res)
+	suite.ErrorIs(err, ErrResultTooLarge)
+}
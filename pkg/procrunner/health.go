@@ -0,0 +1,52 @@
+package procrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errNotIdle fails a health check ping that is not sent because the runner is not idle.
+var errNotIdle = fmt.Errorf("not idle")
+
+// HealthCheckOption pings the process every Interval while the runner is idle, and closes
+// the runner if the process does not respond within Timeout, e.g. stopped or stuck in a GC,
+// or if it has exited. A ProcRunnerPool created with the option checks all its runners from
+// a single janitor, so that a closed runner is evicted before it serves a request, see
+// ProcRunner.IsClosed.
+type HealthCheckOption struct {
+	Interval time.Duration
+	// Timeout bounds each ping, the Interval if 0.
+	Timeout time.Duration
+}
+
+func (o HealthCheckOption) apply(r *ProcRunner) {
+	r.healthCheck = &o
+}
+
+// checkHealth pings the process if the runner is idle, and closes the runner if the ping fails.
+func (r *ProcRunner) checkHealth() {
+	timeout := r.healthCheck.Timeout
+	if timeout <= 0 {
+		timeout = r.healthCheck.Interval
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	err := r.ping(ctx, true)
+	cancel()
+	// a busy process is checked by the deadlines of its requests, and a ping sent
+	// behind a request would time out if the request is slow. A process that exited
+	// on its own is retired by the reader.
+	if errors.Is(err, errNotIdle) {
+		return
+	}
+	// a timed out ping has killed the process already.
+	if errors.Is(err, ErrorTimeout) || (err != nil && !r.IsClosed()) {
+		log.Debug().Err(err).Msg("v8 health check failed")
+		r.retire(RetireUnhealthy)
+		r.metrics.IncCounter(MetricHealthCheckFailures)
+		r.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package procrunner
+
+import (
+	"sync"
+	"time"
+)
+
+// The janitor tends the idle runners: every pass, it retires the runners whose recycle
+// limits are reached, and pings the runners whose health check is due. A ProcRunnerPool
+// runs a single janitor, which evaluates all its runners in one pass under the lock of the
+// pool, and a runner created outside of a pool runs its own.
+
+// pooledOption marks a runner created by a ProcRunnerPool, which is tended by the janitor
+// of the pool instead of its own, and replaced by respawn, see MaxRSSOption.
+type pooledOption struct {
+	respawn func() (*ProcRunner, error)
+}
+
+func (o pooledOption) apply(r *ProcRunner) {
+	r.pooled = true
+	r.respawn = o.respawn
+}
+
+// janitorInterval returns how often the janitor must tend the runner, 0 if never.
+func (r *ProcRunner) janitorInterval() time.Duration {
+	var interval time.Duration
+	if r.healthCheck != nil {
+		interval = r.healthCheck.Interval
+	}
+	if r.recycle != nil {
+		recycle := r.recycle.Interval
+		if recycle <= 0 {
+			recycle = defaultRecycleInterval
+		}
+		if interval == 0 || recycle < interval {
+			interval = recycle
+		}
+	}
+	return interval
+}
+
+// sweep evaluates the runner at now: it kills the process of an idle runner whose recycle
+// limit is reached, and reports whether the runner is retired or its health check is due.
+// The retired runner is closed, and the health check done, by the caller once it has
+// released the lock of the pool, if any.
+func (r *ProcRunner) sweep(now time.Time) (retired, due bool) {
+	if r.recycle != nil && r.retireIdle(now) != "" {
+		return true, false
+	}
+	return false, r.healthCheckDue(now)
+}
+
+// healthCheckDue reports whether the health check of the runner is due at now, and if so,
+// records it as checked. It must only be called by the janitor of the runner.
+func (r *ProcRunner) healthCheckDue(now time.Time) bool {
+	if r.healthCheck == nil || r.IsClosed() || now.Sub(r.checkedAt) < r.healthCheck.Interval {
+		return false
+	}
+	r.checkedAt = now
+	return true
+}
+
+// checkHealthAll checks the health of the runners concurrently, so that a stuck process
+// does not delay the checks of the others, and returns once all are checked.
+func checkHealthAll(runners []*ProcRunner) {
+	var wg sync.WaitGroup
+	for _, r := range runners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.checkHealth()
+		}()
+	}
+	wg.Wait()
+}
+
+// tend runs the janitor of a runner created outside of a pool until exited is closed.
+func (r *ProcRunner) tend(exited <-chan struct{}) {
+	ticker := time.NewTicker(r.janitorInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+		retired, due := r.sweep(time.Now())
+		if retired {
+			r.Close()
+			return
+		}
+		if due {
+			r.checkHealth()
+		}
+	}
+}
+
+// startJanitorLocked starts the janitor of the pool if runner needs it and it is not running.
+func (p *ProcRunnerPool) startJanitorLocked(runner *ProcRunner) {
+	if p.tending || runner.janitorInterval() <= 0 {
+		return
+	}
+	p.tending = true
+	go p.tend()
+}
+
+// tend runs the janitor of the pool until none of its runners needs it. It waits for the
+// shortest interval of the runners between passes, so a runner may be evaluated more often
+// than its own interval, and its health checked once its interval has elapsed.
+func (p *ProcRunnerPool) tend() {
+	for {
+		p.mu.Lock()
+		var interval time.Duration
+		for runner := range p.runners {
+			if i := runner.janitorInterval(); i > 0 && (interval == 0 || i < interval) {
+				interval = i
+			}
+		}
+		if interval == 0 {
+			p.tending = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		time.Sleep(interval)
+
+		p.mu.Lock()
+		now := time.Now()
+		var retired, due []*ProcRunner
+		for runner := range p.runners {
+			switch isRetired, isDue := runner.sweep(now); {
+			case isRetired:
+				retired = append(retired, runner)
+			case isDue:
+				due = append(due, runner)
+			}
+		}
+		p.mu.Unlock()
+		// closed and pinged outside of the lock, a closed runner takes it to free its slot.
+		for _, runner := range retired {
+			runner.Close()
+		}
+		checkHealthAll(due)
+	}
+}
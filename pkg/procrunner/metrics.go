@@ -0,0 +1,76 @@
+package procrunner
+
+// Metrics receives the metrics of the lifecycle of runners, see MetricsOption.
+// The names are the Metric constants. Implementations must be safe for concurrent use,
+// and should not block. See the metrics package for expvar and Prometheus implementations.
+type Metrics interface {
+	// IncCounter increments the counter name.
+	IncCounter(name string)
+	// AddGauge adds delta to the gauge name.
+	AddGauge(name string, delta float64)
+	// Observe records value in the histogram name.
+	Observe(name string, value float64)
+}
+
+// The metrics of the runners.
+const (
+	// MetricSpawns counts the processes started.
+	MetricSpawns = "v8runner_spawns_total"
+	// MetricSpawnErrors counts the processes that failed to start.
+	MetricSpawnErrors = "v8runner_spawn_errors_total"
+	// MetricExits counts the processes that exited, whatever the reason.
+	MetricExits = "v8runner_exits_total"
+	// MetricKills counts the processes killed because of a request: a timeout, a canceled
+	// context, or a response exceeding the limits.
+	MetricKills = "v8runner_kills_total"
+	// MetricCrashes counts the processes that exited without being closed, e.g. aborted by V8.
+	MetricCrashes = "v8runner_crashes_total"
+	// MetricHealthCheckFailures counts the runners closed by HealthCheckOption.
+	MetricHealthCheckFailures = "v8runner_health_check_failures_total"
+	// MetricRSSKills counts the processes killed by MaxRSSOption.
+	MetricRSSKills = "v8runner_rss_kills_total"
+	// MetricRetiredIdle, MetricRetiredMaxAge and MetricRetiredMaxExecutions count the runners
+	// retired by RecycleOption, by reason.
+	MetricRetiredIdle          = "v8runner_retired_idle_total"
+	MetricRetiredMaxAge        = "v8runner_retired_max_age_total"
+	MetricRetiredMaxExecutions = "v8runner_retired_max_executions_total"
+	// MetricProcesses is the number of processes running.
+	MetricProcesses = "v8runner_processes"
+
+	// MetricRequests counts the requests sent to the processes.
+	MetricRequests = "v8runner_requests_total"
+	// MetricRequestErrors counts the requests that failed, timeouts included.
+	MetricRequestErrors = "v8runner_request_errors_total"
+	// MetricTimeouts counts the requests that failed with ErrorTimeout.
+	MetricTimeouts = "v8runner_timeouts_total"
+	// MetricInFlight is the number of requests sent and not yet responded to.
+	MetricInFlight = "v8runner_requests_in_flight"
+	// MetricRequestDuration is the time from sending a request to its response, in seconds.
+	MetricRequestDuration = "v8runner_request_duration_seconds"
+	// MetricQueueWait is the time a request waits for the requests sent before it to be
+	// responded to, in seconds. A request starts when the previous response is received.
+	MetricQueueWait = "v8runner_queue_wait_seconds"
+
+	// MetricPoolRunning is the number of runners of ProcRunnerPools.
+	MetricPoolRunning = "v8runner_pool_running"
+	// MetricPoolWaiting is the number of calls of ProcRunnerPool.Acquire waiting for a slot.
+	MetricPoolWaiting = "v8runner_pool_waiting"
+	// MetricPoolRejections counts the runners refused by ProcRunnerPools with ErrMaxReached.
+	MetricPoolRejections = "v8runner_pool_rejections_total"
+)
+
+// MetricsOption reports the metrics of the runner to Metrics.
+type MetricsOption struct {
+	Metrics Metrics
+}
+
+func (o MetricsOption) apply(r *ProcRunner) {
+	r.metrics = o.Metrics
+}
+
+// nopMetrics discards the metrics of a runner without MetricsOption.
+type nopMetrics struct{}
+
+func (nopMetrics) IncCounter(string)        {}
+func (nopMetrics) AddGauge(string, float64) {}
+func (nopMetrics) Observe(string, float64)  {}
@@ -0,0 +1,155 @@
+package procrunner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// recorder is a Metrics recording the values of each name.
+type recorder struct {
+	mu           sync.Mutex
+	counters     map[string]int
+	gauges       map[string]float64
+	observations map[string][]float64
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		counters:     make(map[string]int),
+		gauges:       make(map[string]float64),
+		observations: make(map[string][]float64),
+	}
+}
+
+func (r *recorder) IncCounter(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name]++
+}
+
+func (r *recorder) AddGauge(name string, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[name] += delta
+}
+
+func (r *recorder) Observe(name string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations[name] = append(r.observations[name], value)
+}
+
+func (r *recorder) counter(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[name]
+}
+
+func (r *recorder) gauge(name string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.gauges[name]
+}
+
+func (r *recorder) observed(name string) []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]float64(nil), r.observations[name]...)
+}
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (suite *MetricsTestSuite) TestRequests() {
+	m := newRecorder()
+	runner, err := NewProcRunner("expression.js", 16, MetricsOption{Metrics: m})
+	suite.Require().NoError(err)
+	suite.Equal(1, m.counter(MetricSpawns))
+	suite.Equal(1.0, m.gauge(MetricProcesses))
+
+	_, err = runner.RunCodeJSON(context.Background(), "1+1")
+	suite.NoError(err)
+	_, err = runner.RunCodeJSON(context.Background(), `throw new Error("failed")`)
+	suite.Error(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	slow := runner.RunCodeAsync(ctx, "while(true){}")
+	suite.Equal(1.0, m.gauge(MetricInFlight))
+	queued := runner.RunCodeAsync(context.Background(), "1+1")
+	_, err = slow.Get()
+	suite.Equal(ErrorTimeout, err)
+	_, err = queued.Get()
+	suite.NoError(err)
+
+	suite.Equal(4, m.counter(MetricRequests))
+	suite.Equal(2, m.counter(MetricRequestErrors))
+	suite.Equal(1, m.counter(MetricTimeouts))
+	suite.Equal(0, m.counter(MetricKills))
+	suite.Equal(0.0, m.gauge(MetricInFlight))
+	suite.Len(m.observed(MetricRequestDuration), 4)
+	wait := m.observed(MetricQueueWait)
+	suite.Require().Len(wait, 4)
+	// the queued request waits for the timeout of the slow one.
+	suite.Greater(wait[3], 0.1)
+
+	runner.Close()
+	suite.Eventually(func() bool { return m.counter(MetricExits) == 1 }, time.Second, 10*time.Millisecond)
+	suite.Equal(0.0, m.gauge(MetricProcesses))
+	suite.Equal(0, m.counter(MetricCrashes))
+}
+
+func (suite *MetricsTestSuite) TestKills() {
+	m := newRecorder()
+	runner, err := NewProcRunner("expression.js", 16, MetricsOption{Metrics: m})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	// without a deadline, the process is killed when the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	res := runner.RunCodeAsync(ctx, "while(true){}")
+	cancel()
+	_, err = res.Get()
+	suite.Equal(ErrorTimeout, err)
+	suite.Equal(1, m.counter(MetricKills))
+	suite.Equal(1, m.counter(MetricTimeouts))
+	suite.Equal(0, m.counter(MetricCrashes))
+}
+
+func (suite *MetricsTestSuite) TestSpawnErrors() {
+	m := newRecorder()
+	suite.T().Setenv("PATH", "")
+	_, err := NewProcRunner("expression.js", 16, MetricsOption{Metrics: m})
+	suite.Error(err)
+	suite.Equal(1, m.counter(MetricSpawnErrors))
+	suite.Equal(0, m.counter(MetricSpawns))
+}
+
+func (suite *MetricsTestSuite) TestPool() {
+	m := newRecorder()
+	pool := NewProcRunnerPool(1, MetricsOption{Metrics: m})
+	runner, err := pool.NewRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	suite.Equal(1.0, m.gauge(MetricPoolRunning))
+	_, err = pool.NewRunner("expression.js", 16)
+	suite.ErrorIs(err, ErrMaxReached)
+	suite.Equal(1, m.counter(MetricPoolRejections))
+
+	// the runners of the pool report to the same metrics.
+	_, err = runner.RunCodeJSON(context.Background(), "1+1")
+	suite.NoError(err)
+	suite.Equal(1, m.counter(MetricRequests))
+
+	runner.Close()
+	suite.Eventually(func() bool { return m.gauge(MetricPoolRunning) == 0 }, time.Second, 10*time.Millisecond)
+	suite.Equal(1, m.counter(MetricExits))
+}
@@ -0,0 +1,287 @@
+package procrunner
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/stumble/v8runner/pkg/types"
+)
+
+// Option configures a ProcRunner.
+type Option interface {
+	apply(r *ProcRunner)
+}
+
+// MaxStreamSizeOption bounds the total size of the values yielded by one RunCodeStream.
+// Bytes is the sum of the lengths of the JSON values, 0 means no limit.
+type MaxStreamSizeOption struct {
+	Bytes int
+}
+
+func (o MaxStreamSizeOption) apply(r *ProcRunner) {
+	r.maxStreamSize = o.Bytes
+}
+
+// defaultGracePeriod is the grace period of a ProcRunner without GracePeriodOption.
+const defaultGracePeriod = time.Second
+
+// GracePeriodOption is how long the runner waits for the process to respond once the
+// deadline of a request is exceeded, before killing it. The process terminates the script
+// on the deadline by itself, so it is only exceeded when the process is unresponsive.
+type GracePeriodOption struct {
+	Duration time.Duration
+}
+
+func (o GracePeriodOption) apply(r *ProcRunner) {
+	r.gracePeriod = o.Duration
+}
+
+// HeapHeadroomOption is how much the heap of the process can grow beyond its max heap
+// size, so that a script exceeding the max heap size is terminated with ErrOutOfMemory
+// without aborting the process. 0 disables it. The process uses 16 MB by default.
+type HeapHeadroomOption struct {
+	MB uint
+}
+
+func (o HeapHeadroomOption) apply(r *ProcRunner) {
+	r.heapHeadroom = &o.MB
+}
+
+// SnapshotOption boots the process from a V8 startup snapshot created by
+// `v8runner snapshot --bootstrap lib.js -o lib.snap`, so that the libraries of the
+// bootstrap script are defined before the first request without running the script.
+// The process boots from it again when it is reset by ErrOutOfMemory.
+type SnapshotOption struct {
+	Path string
+}
+
+func (o SnapshotOption) apply(r *ProcRunner) {
+	r.snapshot = o.Path
+}
+
+// ScriptCacheOption configures the cache of the scripts compiled by the process, so that
+// running the same code again skips parsing and compiling it. Size is the number of
+// scripts kept, 0 disables the cache, the process keeps 128 by default. If Dir is set,
+// the V8 code cache of the scripts is persisted to Dir, so that new processes sharing
+// it skip parsing the scripts they have never run. See ProcRunner.ScriptCacheStats.
+type ScriptCacheOption struct {
+	Size int
+	Dir  string
+}
+
+func (o ScriptCacheOption) apply(r *ProcRunner) {
+	r.scriptCache = &o
+}
+
+func (o *ScriptCacheOption) args() []string {
+	if o == nil {
+		return nil
+	}
+	args := []string{"--script-cache-size", strconv.Itoa(o.Size)}
+	if o.Dir != "" {
+		args = append(args, "--code-cache-dir", o.Dir)
+	}
+	return args
+}
+
+// PolyfillsOption defines the web platform APIs missing from V8 in the process:
+// TextEncoder, TextDecoder, atob, btoa, URL, URLSearchParams and structuredClone.
+// See runner.PolyfillsOption.
+type PolyfillsOption struct{}
+
+func (o PolyfillsOption) apply(r *ProcRunner) {
+	r.polyfills = true
+}
+
+// DeterministicOption replaces the non-deterministic APIs of the process: Math.random is
+// seeded by WithSeed, and Date.now, new Date() and performance.now read the virtual clock
+// of WithClock, so that a request sent again with the same seed and clock yields the same
+// result. The requests without them use the seed 0 and a clock fixed at the Unix epoch.
+// The process runs in the UTC time zone, so that local dates are deterministic too.
+// See runner.DeterministicOption.
+type DeterministicOption struct{}
+
+func (o DeterministicOption) apply(r *ProcRunner) {
+	r.deterministic = true
+}
+
+// LockdownOption restricts the language surface of the process, for untrusted scripts.
+// See runner.LockdownOption.
+type LockdownOption struct {
+	// NoCodeGeneration makes eval and new Function throw an EvalError.
+	NoCodeGeneration bool
+	// NoWebAssembly removes WebAssembly.
+	NoWebAssembly bool
+	// NoSharedMemory removes SharedArrayBuffer and Atomics.
+	NoSharedMemory bool
+	// FreezeIntrinsics freezes the built-in objects, e.g. Array.prototype.
+	FreezeIntrinsics bool
+}
+
+func (o LockdownOption) apply(r *ProcRunner) {
+	r.lockdown = o
+}
+
+func (o LockdownOption) args() []string {
+	var args []string
+	if o.NoCodeGeneration {
+		args = append(args, "--no-code-generation")
+	}
+	if o.NoWebAssembly {
+		args = append(args, "--no-wasm")
+	}
+	if o.NoSharedMemory {
+		args = append(args, "--no-shared-memory")
+	}
+	if o.FreezeIntrinsics {
+		args = append(args, "--freeze-intrinsics")
+	}
+	return args
+}
+
+// FilesOption is the read-only virtual filesystem of the CommonJS modules of the process,
+// sent to the process at startup. The scripts load the modules with a global require
+// function, resolved like Node, and have no access to the real disk. The files are the
+// files of FS, e.g. an os.DirFS or an embed.FS, and then those of Files, by path.
+// See runner.Runner.SetFiles.
+type FilesOption struct {
+	FS    fs.FS
+	Files map[string]string
+}
+
+func (o FilesOption) apply(r *ProcRunner) {
+	r.files = &o
+}
+
+// read returns the contents of the files, by path.
+func (o *FilesOption) read() (map[string]string, error) {
+	if o == nil {
+		return nil, nil
+	}
+	files := make(map[string]string, len(o.Files))
+	if o.FS != nil {
+		err := fs.WalkDir(o.FS, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(o.FS, name)
+			if err != nil {
+				return err
+			}
+			files[name] = string(data)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read files because: %w", err)
+		}
+	}
+	for name, source := range o.Files {
+		files[name] = source
+	}
+	return files, nil
+}
+
+// LimitsOption bounds the sizes of the requests and responses of a ProcRunner, so that
+// a script cannot exhaust the memory of the caller. All sizes are in bytes, 0 means no limit.
+// The process enforces them before sending a response, and the runner again while decoding.
+type LimitsOption struct {
+	// MaxCodeSize bounds the code of a request, longer code fails with ErrCodeTooLarge
+	// without being sent.
+	MaxCodeSize int
+	// MaxResultSize bounds the JSON result of a request, the sum of the results of a
+	// MapJSON, and each value of a RunCodeStream. Larger results fail with ErrResultTooLarge.
+	MaxResultSize int
+	// MaxLogSize bounds each error message, and the stderr of the process that is logged.
+	// Longer messages are truncated.
+	MaxLogSize int
+	// NOTE: the runner can bound the responses while decoding only when both
+	// MaxResultSize and MaxLogSize are set.
+}
+
+func (o LimitsOption) apply(r *ProcRunner) {
+	r.limits = o
+}
+
+func (o LimitsOption) args() []string {
+	var args []string
+	if o.MaxCodeSize > 0 {
+		args = append(args, "--max-code-size", strconv.Itoa(o.MaxCodeSize))
+	}
+	if o.MaxResultSize > 0 {
+		args = append(args, "--max-result-size", strconv.Itoa(o.MaxResultSize))
+	}
+	if o.MaxLogSize > 0 {
+		args = append(args, "--max-log-size", strconv.Itoa(o.MaxLogSize))
+	}
+	return args
+}
+
+const (
+	// frameOverhead is the room left in a message for everything but the result and the error.
+	frameOverhead = 4096
+	// itemOverhead is the room left in a message for the encoding of each item of a map.
+	itemOverhead = 16
+)
+
+// frameSize is the maximum size of a message responding to a request with the given
+// number of inputs, 0 means no limit.
+func (o LimitsOption) frameSize(inputs int) int {
+	if o.MaxResultSize <= 0 || o.MaxLogSize <= 0 {
+		return 0
+	}
+	return o.MaxResultSize + o.MaxLogSize + frameOverhead + inputs*itemOverhead
+}
+
+// RequestOption customizes a single request sent to the process.
+type RequestOption func(req *types.RunCodeRequest)
+
+// WithItemTimeout bounds each call of a MapJSON request.
+// The process terminates a call that runs longer than d and moves on to the next item.
+func WithItemTimeout(d time.Duration) RequestOption {
+	return func(req *types.RunCodeRequest) {
+		req.ItemTimeout = d
+	}
+}
+
+// WithTimeout bounds a MapJSON request as a whole. Items that have not started
+// when d is exceeded fail without being called, and the results of the finished
+// items are still returned. Unlike the deadline of ctx, it does not kill the process.
+func WithTimeout(d time.Duration) RequestOption {
+	return func(req *types.RunCodeRequest) {
+		req.Timeout = d
+	}
+}
+
+// WithCPUBudget bounds the CPU time the process spends executing the request, e.g. all
+// the calls of a MapJSON. It is measured on the thread running the script, so unlike the
+// deadline of ctx it does not count the time the process waits to be scheduled.
+// A request exceeding it fails with ErrCPUBudgetExceeded, without killing the process.
+func WithCPUBudget(d time.Duration) RequestOption {
+	return func(req *types.RunCodeRequest) {
+		req.CPUBudget = d
+	}
+}
+
+// WithSeed seeds Math.random for the request, see DeterministicOption.
+func WithSeed(seed int64) RequestOption {
+	return func(req *types.RunCodeRequest) {
+		if req.Determinism == nil {
+			req.Determinism = &types.Determinism{}
+		}
+		req.Determinism.Seed = seed
+	}
+}
+
+// WithClock sets the virtual clock of the request to t, advancing by step each time it is
+// read, 0 for a fixed clock. See DeterministicOption.
+func WithClock(t time.Time, step time.Duration) RequestOption {
+	return func(req *types.RunCodeRequest) {
+		if req.Determinism == nil {
+			req.Determinism = &types.Determinism{}
+		}
+		req.Determinism.Clock = t
+		req.Determinism.ClockStep = step
+	}
+}
@@ -1,11 +1,16 @@
 package procrunner
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
-var ErrMaxReached = fmt.Errorf("max reached")
+var (
+	ErrMaxReached   = fmt.Errorf("max reached")
+	ErrPoolShutdown = fmt.Errorf("pool is shut down")
+)
 
 // ProcRunnerPool is a manager that manages a pool of ProcRunner.
 // It can safely enforce the global memory limit by limiting the number of concurrent ProcRunners.
@@ -13,13 +18,42 @@ type ProcRunnerPool struct {
 	running int
 	max     int
 	mu      sync.Mutex
+
+	options []Option
+	metrics Metrics
+
+	// runners are the runners not closed yet, shutdown is set by Shutdown.
+	runners  map[*ProcRunner]struct{}
+	shutdown bool
+	// tending is set while the janitor of the pool runs, see janitor.go.
+	tending bool
+
+	// tenants are the tenants of Acquire, see tenant.go.
+	tenants       map[string]*tenant
+	defaultTenant TenantConfig
+	// pass is the pass of the tenant last granted a slot, seq orders the waiters.
+	pass float64
+	seq  uint64
 }
 
-func NewProcRunnerPool(maxConcurrent int) *ProcRunnerPool {
+// NewProcRunnerPool creates a pool of at most maxConcurrent runners, which are created
+// with options. The pool reports its metrics to the Metrics of MetricsOption, if any.
+func NewProcRunnerPool(maxConcurrent int, options ...Option) *ProcRunnerPool {
+	var scratch ProcRunner
+	for _, opt := range options {
+		opt.apply(&scratch)
+	}
+	if scratch.metrics == nil {
+		scratch.metrics = nopMetrics{}
+	}
 	return &ProcRunnerPool{
 		running: 0,
 		max:     maxConcurrent,
 		mu:      sync.Mutex{},
+		options: options,
+		metrics: scratch.metrics,
+		runners: make(map[*ProcRunner]struct{}),
+		tenants: make(map[string]*tenant),
 	}
 }
 
@@ -33,21 +67,154 @@ func (p *ProcRunnerPool) Max() int {
 	return p.max
 }
 
-func (p *ProcRunnerPool) NewRunner(filename string, maxheapsizemb uint) (*ProcRunner, error) {
+// NewRunner creates a runner with the options of the pool, followed by options,
+// or fails with ErrMaxReached if the pool is full, or ErrPoolShutdown once it is shut down.
+// It does not wait for a slot, and fails with ErrMaxReached too while calls of Acquire are
+// waiting, so that the slots freed meanwhile are kept for the tenants in the queue.
+func (p *ProcRunnerPool) NewRunner(filename string, maxheapsizemb uint, options ...Option) (*ProcRunner, error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-	if p.running >= p.max {
+	if p.shutdown {
+		p.mu.Unlock()
+		return nil, ErrPoolShutdown
+	}
+	if p.running >= p.max || p.waitingLocked() {
+		p.mu.Unlock()
+		p.metrics.IncCounter(MetricPoolRejections)
 		return nil, ErrMaxReached
 	}
-	runner, err := NewProcRunner(filename, maxheapsizemb)
+	p.reserveLocked(nil, 0)
+	p.mu.Unlock()
+	return p.start(nil, filename, maxheapsizemb, options)
+}
+
+// start creates a runner for a slot reserved for t, nil for a runner without tenant.
+// The slot is freed if it fails.
+func (p *ProcRunnerPool) start(t *tenant, filename string, maxheapsizemb uint, options []Option) (*ProcRunner, error) {
+	// a replacement takes over the slot, which it frees like the runner.
+	respawn := func() (*ProcRunner, error) {
+		return p.start(t, filename, maxheapsizemb, options)
+	}
+	all := append(append(p.options[:len(p.options):len(p.options)], options...), pooledOption{respawn: respawn})
+	runner, err := NewProcRunner(filename, maxheapsizemb, all...)
+	p.mu.Lock()
 	if err != nil {
+		p.releaseLocked(t, maxheapsizemb)
+		p.mu.Unlock()
 		return nil, err
 	}
+	if p.shutdown {
+		// shut down meanwhile, the runner would not be closed by Shutdown.
+		p.releaseLocked(t, maxheapsizemb)
+		p.mu.Unlock()
+		runner.Close()
+		return nil, ErrPoolShutdown
+	}
+	p.runners[runner] = struct{}{}
+	p.startJanitorLocked(runner)
 	runner.AddPostCloseFn(func() {
 		p.mu.Lock()
 		defer p.mu.Unlock()
-		p.running--
+		delete(p.runners, runner)
+		// the slot of a runner killed by MaxRSSOption is taken over by its replacement.
+		if !runner.replacing.Load() {
+			p.releaseLocked(t, maxheapsizemb)
+		}
 	})
-	p.running++
+	p.mu.Unlock()
 	return runner, nil
 }
+
+// reserveLocked takes a slot for a runner of t with a heap of maxheapsizemb.
+func (p *ProcRunnerPool) reserveLocked(t *tenant, maxheapsizemb uint) {
+	p.running++
+	p.metrics.AddGauge(MetricPoolRunning, 1)
+	if t != nil {
+		t.running++
+		t.memoryMB += maxheapsizemb
+		t.acquired++
+	}
+}
+
+// releaseLocked frees the slot of a runner of t, and grants it to the next waiter.
+func (p *ProcRunnerPool) releaseLocked(t *tenant, maxheapsizemb uint) {
+	p.running--
+	p.metrics.AddGauge(MetricPoolRunning, -1)
+	if t != nil {
+		t.running--
+		t.memoryMB -= maxheapsizemb
+		p.dropLocked(t)
+	}
+	p.dispatchLocked()
+}
+
+// ShutdownSummary reports how Shutdown closed the runners of a pool.
+type ShutdownSummary struct {
+	// Idle is the number of runners without pending requests, closed right away.
+	Idle int
+	// Drained is the number of runners closed once their pending requests were responded to.
+	Drained int
+	// Killed is the number of runners killed at the deadline, whose pending requests
+	// failed with ErrorKilled.
+	Killed int
+	// Duration is how long the shutdown took.
+	Duration time.Duration
+}
+
+// Shutdown shuts the pool down gracefully: it stops creating runners and accepting requests,
+// which fail with ErrPoolShutdown, as do the calls of Acquire waiting for a slot. It closes
+// the idle runners, and closes the others once their pending requests are responded to.
+// The runners still busy when ctx is done are killed, and Shutdown returns the error of ctx.
+// Once it returns, every runner of the pool is closed.
+func (p *ProcRunnerPool) Shutdown(ctx context.Context) (ShutdownSummary, error) {
+	start := time.Now()
+	p.mu.Lock()
+	p.shutdown = true
+	p.failWaitersLocked(ErrPoolShutdown)
+	runners := make([]*ProcRunner, 0, len(p.runners))
+	for runner := range p.runners {
+		runners = append(runners, runner)
+	}
+	p.mu.Unlock()
+
+	var summary ShutdownSummary
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, runner := range runners {
+		drained := runner.drain()
+		select {
+		case <-drained:
+			runner.Close()
+			summary.Idle++
+			continue
+		default:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			killed := false
+			select {
+			case <-drained:
+			case <-ctx.Done():
+				select {
+				case <-drained:
+				default:
+					killed = true
+				}
+			}
+			runner.Close()
+			mu.Lock()
+			defer mu.Unlock()
+			if killed {
+				summary.Killed++
+			} else {
+				summary.Drained++
+			}
+		}()
+	}
+	wg.Wait()
+	summary.Duration = time.Since(start)
+	if summary.Killed > 0 {
+		return summary, ctx.Err()
+	}
+	return summary, nil
+}
@@ -1,7 +1,10 @@
 package procrunner
 
 import (
+	"context"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -39,3 +42,100 @@ func (suite *ProcRunnerPoolTestSuite) TestConcurrency() {
 	runner4.Close()
 	suite.Equal(0, pool.Running())
 }
+
+func (suite *ProcRunnerPoolTestSuite) TestHealthCheck() {
+	pool := NewProcRunnerPool(2, HealthCheckOption{Interval: 20 * time.Millisecond, Timeout: 50 * time.Millisecond})
+	healthy, err := pool.NewRunner("test.js", 32)
+	suite.Require().NoError(err)
+	defer healthy.Close()
+	stopped, err := pool.NewRunner("test.js", 32)
+	suite.Require().NoError(err)
+	defer stopped.Close()
+
+	suite.Require().NoError(stopped.cmd.Process.Signal(syscall.SIGSTOP))
+	suite.Eventually(stopped.IsClosed, 2*time.Second, 10*time.Millisecond)
+	suite.Eventually(func() bool { return pool.Running() == 1 }, time.Second, 10*time.Millisecond)
+	suite.False(healthy.IsClosed())
+	res, err := healthy.RunCodeJSON(context.Background(), "1+1")
+	suite.NoError(err)
+	suite.Equal("2", res)
+}
+
+func (suite *ProcRunnerPoolTestSuite) TestJanitor() {
+	// the option of a single runner is tended by the janitor of the pool too.
+	pool := NewProcRunnerPool(2)
+	plain, err := pool.NewRunner("test.js", 32)
+	suite.Require().NoError(err)
+	defer plain.Close()
+	suite.False(pool.tendingNow())
+	checked, err := pool.NewRunner("test.js", 32, HealthCheckOption{Interval: 20 * time.Millisecond, Timeout: 50 * time.Millisecond})
+	suite.Require().NoError(err)
+	defer checked.Close()
+	suite.True(pool.tendingNow())
+
+	suite.Require().NoError(checked.cmd.Process.Signal(syscall.SIGSTOP))
+	// the timed out ping kills the process before the runner is retired.
+	suite.Eventually(func() bool { return checked.RetireReason() == RetireUnhealthy }, 2*time.Second, 10*time.Millisecond)
+	suite.True(checked.IsClosed())
+	// the janitor stops once no runner needs it.
+	suite.Eventually(func() bool { return !pool.tendingNow() }, time.Second, 10*time.Millisecond)
+	suite.False(plain.IsClosed())
+}
+
+func (suite *ProcRunnerPoolTestSuite) TestShutdown() {
+	pool := NewProcRunnerPool(3)
+	idle, err := pool.NewRunner("test.js", 32)
+	suite.Require().NoError(err)
+	busy, err := pool.NewRunner("test.js", 32)
+	suite.Require().NoError(err)
+	stuck, err := pool.NewRunner("test.js", 32)
+	suite.Require().NoError(err)
+
+	finished := busy.RunCodeAsync(context.Background(), "const end = Date.now() + 200; while (Date.now() < end) {}; 1")
+	killed := stuck.RunCodeAsync(context.Background(), "while (true) {}")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	var summary ShutdownSummary
+	go func() {
+		defer close(done)
+		summary, err = pool.Shutdown(ctx)
+	}()
+	// no new work is admitted meanwhile.
+	suite.Eventually(idle.IsClosed, time.Second, 10*time.Millisecond)
+	_, err2 := pool.NewRunner("test.js", 32)
+	suite.ErrorIs(err2, ErrPoolShutdown)
+	_, err2 = busy.RunCodeJSON(context.Background(), "1")
+	suite.ErrorIs(err2, ErrorClosed)
+	suite.ErrorIs(err2, ErrPoolShutdown)
+
+	<-done
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	suite.Equal(1, summary.Idle)
+	suite.Equal(1, summary.Drained)
+	suite.Equal(1, summary.Killed)
+	// measured from the start of Shutdown, a little after the deadline was set.
+	suite.GreaterOrEqual(summary.Duration, 450*time.Millisecond)
+
+	res, err := finished.Get()
+	suite.NoError(err)
+	suite.Equal("1", res)
+	_, err = killed.Get()
+	suite.ErrorIs(err, ErrorKilled)
+	suite.Equal(0, pool.Running())
+	suite.True(busy.IsClosed())
+	suite.True(stuck.IsClosed())
+
+	// a pool without runners shuts down right away.
+	summary, err = NewProcRunnerPool(1).Shutdown(context.Background())
+	suite.NoError(err)
+	suite.Equal(ShutdownSummary{Duration: summary.Duration}, summary)
+}
+
+// tendingNow reports whether the janitor of the pool is running.
+func (p *ProcRunnerPool) tendingNow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tending
+}
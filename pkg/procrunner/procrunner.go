@@ -1,14 +1,19 @@
 package procrunner
 
 import (
-	// "bufio"
+	"bufio"
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/stumble/v8runner/pkg/types"
@@ -18,11 +23,58 @@ var (
 	ErrorTimeout = fmt.Errorf("timeout")
 	ErrorClosed  = fmt.Errorf("closed")
 	ErrorKilled  = fmt.Errorf("killed")
+
+	ErrStreamTooLarge = fmt.Errorf("stream too large")
+	ErrCodeTooLarge   = fmt.Errorf("code too large")
+	ErrResultTooLarge = fmt.Errorf("result too large")
+
+	ErrCPUBudgetExceeded = fmt.Errorf("cpu budget exceeded")
+	ErrOutOfMemory       = fmt.Errorf("out of memory")
+	ErrUnknownScript     = fmt.Errorf("unknown script")
+	ErrModuleNotFound    = fmt.Errorf("module not found")
 )
 
+// errCodes maps the error codes of the responses to the errors returned to the caller.
+var errCodes = map[types.ErrCode]error{
+	types.ErrCodeTimeout:        ErrorTimeout,
+	types.ErrCodeStreamTooLarge: ErrStreamTooLarge,
+	types.ErrCodeCodeTooLarge:   ErrCodeTooLarge,
+	types.ErrCodeResultTooLarge: ErrResultTooLarge,
+
+	types.ErrCodeCPUBudgetExceeded: ErrCPUBudgetExceeded,
+	types.ErrCodeOutOfMemory:       ErrOutOfMemory,
+	types.ErrCodeUnknownScript:     ErrUnknownScript,
+	types.ErrCodeModuleNotFound:    ErrModuleNotFound,
+}
+
+// responseError converts the error message and code of a response to error.
+// A timeout is the bare ErrorTimeout, whether the process terminated the script or was
+// killed, so that err == ErrorTimeout holds like before the process could terminate it.
+func responseError(msg string, code types.ErrCode) error {
+	if code == types.ErrCodeTimeout {
+		return ErrorTimeout
+	}
+	return codeError(msg, code)
+}
+
+// codeError converts the error message and code of a response or of an item to error,
+// which wraps the error of the code and keeps the details of the message.
+func codeError(msg string, code types.ErrCode) error {
+	if err, ok := errCodes[code]; ok {
+		// the message usually starts with the same error in the process
+		if rest, found := strings.CutPrefix(msg, err.Error()); found {
+			return fmt.Errorf("%w%s", err, rest)
+		}
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
 // ProcRunner is a runner that spawn a new process to run v8 js.
 // It can safely enforce the global memory limit and per-request timeout.
-// ProcRunner is not supposed to be used concurrently, although it is safe to do so.
+// ProcRunner is safe to use concurrently: requests are written to the process in
+// the order they are submitted and executed one by one, so callers can pipeline
+// requests with RunCodeAsync to amortize the IPC latency.
 // ProcRunner must be closed after use.
 type ProcRunner struct {
 	cmd     *exec.Cmd
@@ -31,29 +83,229 @@ type ProcRunner struct {
 	stderr  io.ReadCloser
 	encoder *gob.Encoder
 	decoder *gob.Decoder
+	// written counts the bytes written to stdin, timer times the responses read from stdout.
+	written *countingWriter
+	timer   *readTimer
+
+	// writeMu serializes writes to the process, it must never be held while
+	// waiting for a response, otherwise the process may block on a full stdout.
+	writeMu sync.Mutex
+
+	// mu guards seq, pending, order, readErr, executions, lastActive, drained and reaped.
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]*call
+	// order holds the pending calls in the order they were sent, and may still
+	// hold calls already responded to, which oldestLocked drops.
+	order   []*call
+	readErr error
+	// executions counts the requests sent but pings, lastActive is when the last one was
+	// sent or responded to.
+	executions int
+	lastActive time.Time
+	// drained is set by drain, and closed once no request is pending.
+	drained chan struct{}
+	// reaped is set right before the process is waited for, after which its PID may be reused.
+	reaped bool
+
+	maxStreamSize int
+	limits        LimitsOption
+	gracePeriod   time.Duration
+	heapHeadroom  *uint
+	snapshot      string
+	scriptCache   *ScriptCacheOption
+	files         *FilesOption
+	polyfills     bool
+	deterministic bool
+	lockdown      LockdownOption
+	metrics       Metrics
+	tracer        Tracer
+	healthCheck   *HealthCheckOption
+	maxRSS        *MaxRSSOption
+	recycle       *RecycleOption
+	// pooled is set for a runner of a ProcRunnerPool, checkedAt is when the janitor
+	// last checked its health, see janitor.go.
+	pooled    bool
+	checkedAt time.Time
+
+	// cacheMu guards cacheStats, the sum of the script cache stats of the responses.
+	cacheMu    sync.Mutex
+	cacheStats types.ScriptCacheStats
 
-	mu  sync.Mutex
-	seq uint64
+	// stderrDone is closed once stderr is consumed, oom is set if it reports that
+	// V8 aborted the process because it ran out of memory.
+	stderrDone chan struct{}
+	oom        atomic.Bool
+	// rssExceeded is set if the process is killed by MaxRSSOption, and replacing once it is
+	// replaced by replacement, which is spawned by respawn.
+	rssExceeded atomic.Bool
+	replacing   atomic.Bool
+	replacement atomic.Pointer[ProcRunner]
+	respawn     func() (*ProcRunner, error)
 
 	wg      sync.WaitGroup
 	closeFn func()
 	closed  atomic.Bool
 
+	// createdAt is when the process was started, retired is why the runner closed itself.
+	createdAt time.Time
+	retired   atomic.Pointer[RetireReason]
+
 	postCloseFn []func()
 }
 
+// call is a request that has been sent to the process and is waiting for a response.
+type call struct {
+	id       string
+	maxFrame int
+	// items receives the responses of a stream but the last one, nil for other requests.
+	items    chan types.RunCodeResponse
+	once     sync.Once
+	done     chan struct{}
+	res      types.RunCodeResponse
+	err      error
+	stop     func() bool
+	timedOut atomic.Bool
+	// sent is when the request was sent, started is set once the requests sent before it
+	// are responded to, guarded by the mu of the runner.
+	sent    time.Time
+	started bool
+	// onResolve is called once the call is resolved, if set.
+	onResolve func(res types.RunCodeResponse, err error)
+	// trace holds the spans of the call, nil without TracerOption.
+	trace *trace
+	// ping is set for the pings, which are not requests of the caller.
+	ping bool
+}
+
+func (c *call) resolve(res types.RunCodeResponse, err error) {
+	c.once.Do(func() {
+		c.res = res
+		c.err = err
+		if c.trace != nil {
+			// the spans end before the result is returned to the caller.
+			c.trace.resolve(res, err)
+		}
+		close(c.done)
+		if c.onResolve != nil {
+			c.onResolve(res, err)
+		}
+	})
+}
+
+func (c *call) finish(res types.RunCodeResponse, err error) {
+	c.stop()
+	if err != nil && c.timedOut.Load() {
+		// resolved with ErrorTimeout once the runner is closed.
+		return
+	}
+	c.resolve(res, err)
+}
+
+// drain discards the remaining items of a stream, the reader blocks on
+// items until they are received.
+func (c *call) drain() {
+	for {
+		select {
+		case <-c.items:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// wait blocks until the response is available, and converts a response error to error.
+func (c *call) wait() (types.RunCodeResponse, error) {
+	<-c.done
+	if c.err != nil {
+		return c.res, c.err
+	}
+	if c.res.Error != nil {
+		return c.res, responseError(*c.res.Error, c.res.ErrorCode)
+	}
+	return c.res, nil
+}
+
+// Future is the result of a request submitted by RunCodeAsync.
+type Future struct {
+	c *call
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.c.done
+}
+
+// Get blocks until the result is available and returns it.
+// The possible outcomes are the same as RunCodeJSON.
+func (f *Future) Get() (string, error) {
+	res, err := f.c.wait()
+	if err != nil {
+		return "", err
+	}
+	if res.Result == nil {
+		// only possible for a RtnValueTypeNil request
+		return "", nil
+	}
+	return *res.Result, nil
+}
+
 // NewProcRunner creates a new ProcRunner that runs the given file.
-func NewProcRunner(fileName string, maxHeapSizeMB uint) (*ProcRunner, error) {
+func NewProcRunner(fileName string, maxHeapSizeMB uint, options ...Option) (*ProcRunner, error) {
+	proc := &ProcRunner{
+		pending:     make(map[string]*call),
+		gracePeriod: defaultGracePeriod,
+		createdAt:   time.Now(),
+	}
+	proc.lastActive = proc.createdAt
+	proc.checkedAt = proc.createdAt
+	for _, opt := range options {
+		opt.apply(proc)
+	}
+	if proc.metrics == nil {
+		proc.metrics = nopMetrics{}
+	}
+	if proc.respawn == nil {
+		proc.respawn = func() (*ProcRunner, error) {
+			return NewProcRunner(fileName, maxHeapSizeMB, options...)
+		}
+	}
+	files, err := proc.files.read()
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the command
 	// Should be safe to pass these parameters because they are not user input.
-	//nolint:gosec // G204: Parameters are controlled and validated
-	cmd := exec.Command(
-		"v8runner",
+	args := []string{
 		"--file",
 		fileName,
 		"--max-heap",
 		fmt.Sprintf("%d", maxHeapSizeMB),
-	)
+	}
+	if proc.heapHeadroom != nil {
+		args = append(args, "--heap-headroom", fmt.Sprintf("%d", *proc.heapHeadroom))
+	}
+	if proc.snapshot != "" {
+		args = append(args, "--snapshot", proc.snapshot)
+	}
+	if proc.polyfills {
+		args = append(args, "--polyfills")
+	}
+	if proc.deterministic {
+		args = append(args, "--deterministic")
+	}
+	args = append(args, proc.lockdown.args()...)
+	if proc.tracer != nil {
+		args = append(args, "--trace-log")
+	}
+	args = append(args, proc.scriptCache.args()...)
+	args = append(args, proc.limits.args()...)
+	//nolint:gosec // G204: Parameters are controlled and validated
+	cmd := exec.Command("v8runner", args...)
+	if proc.deterministic {
+		cmd.Env = append(os.Environ(), "TZ=UTC")
+	}
 
 	// Set up the stdin, stdout, stderr
 	stdin, err := cmd.StdinPipe()
@@ -71,52 +323,143 @@ func NewProcRunner(fileName string, maxHeapSizeMB uint) (*ProcRunner, error) {
 
 	// Start the process
 	if err := cmd.Start(); err != nil {
+		proc.metrics.IncCounter(MetricSpawnErrors)
 		return nil, err
 	}
+	proc.metrics.IncCounter(MetricSpawns)
+	proc.metrics.AddGauge(MetricProcesses, 1)
 
-	proc := &ProcRunner{
-		cmd:     cmd,
-		stdin:   stdin,
-		stdout:  stdout,
-		stderr:  stderr,
-		encoder: gob.NewEncoder(stdin),
-		decoder: gob.NewDecoder(stdout),
-		closeFn: sync.OnceFunc(func() {
-			err := cmd.Process.Kill()
-			if err != nil {
-				log.Debug().Err(err).Msgf("v8 kill failed")
-			}
-		}),
+	proc.cmd = cmd
+	proc.stdin = stdin
+	proc.stdout = stdout
+	proc.stderr = stderr
+	proc.written = &countingWriter{w: stdin}
+	proc.encoder = gob.NewEncoder(proc.written)
+	proc.timer = &readTimer{r: stdout}
+	if proc.limits.frameSize(0) > 0 {
+		proc.decoder = gob.NewDecoder(newFrameLimitReader(proc.timer, proc.nextFrameSize))
+	} else {
+		proc.decoder = gob.NewDecoder(proc.timer)
+	}
+	proc.closeFn = sync.OnceFunc(func() {
+		proc.closed.Store(true)
+		err := cmd.Process.Kill()
+		if err != nil {
+			log.Debug().Err(err).Msgf("v8 kill failed")
+		}
+	})
+
+	readerDone := make(chan struct{})
+	// created before the reader, which waits for it once the process exits.
+	proc.stderrDone = make(chan struct{})
+	proc.wg.Add(1)
+	// the only reader of stdout, dispatches responses to the pending calls by ID.
+	go func() {
+		defer proc.wg.Done()
+		defer close(readerDone)
+		proc.readLoop()
+	}()
+
+	// handle stderr, the process would block if nobody reads it.
+	proc.wg.Add(1)
+	go func() {
+		defer proc.wg.Done()
+		defer close(proc.stderrDone)
+		proc.logStderr()
+	}()
+
+	if !proc.pooled && proc.janitorInterval() > 0 {
+		// not waited for by Close, which the janitor calls, it returns once the process exits.
+		go proc.tend(readerDone)
+	}
+	if proc.maxRSS != nil {
+		go proc.limitRSS(readerDone)
 	}
 
 	proc.wg.Add(1)
 	// uses Wait() to handle SIGCHLD to avoid zombie process.
 	go func() {
 		defer proc.wg.Done()
+		// Wait closes stdout and stderr, so it must not be called before the readers are done.
+		<-readerDone
+		<-proc.stderrDone
+		proc.mu.Lock()
+		// the PID may be reused once the process is reaped, see Stats.
+		proc.reaped = true
+		proc.mu.Unlock()
 		_ = cmd.Wait()
 		proc.closed.Store(true)
+		proc.metrics.IncCounter(MetricExits)
+		proc.metrics.AddGauge(MetricProcesses, -1)
 		// call postCloseFn only after the process is killed
 		for _, f := range proc.postCloseFn {
 			f()
 		}
 	}()
-	// // handle stderr
-	// proc.wg.Add(1)
-	// go func() {
-	// 	defer proc.wg.Done()
-	// 	// Create a scanner to read stderr line by line
-	// 	scanner := bufio.NewScanner(stderr)
-	// 	for scanner.Scan() {
-	// 		log.Debug().Msgf("v8 stderr: %s", scanner.Text())
-	// 	}
-	// 	// Check for errors in scanning
-	// 	if err := scanner.Err(); err != nil {
-	// 		log.Error().Err(err).Msg("error reading stderr")
-	// 	}
-	// }()
+
+	if files != nil {
+		// sent before any other request, so that require is defined for all of them.
+		if err := proc.sendFiles(files); err != nil {
+			proc.Close()
+			return nil, err
+		}
+	}
 	return proc, nil
 }
 
+// sendFiles sends the virtual filesystem of FilesOption to the process.
+func (r *ProcRunner) sendFiles(files map[string]string) error {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypeFiles,
+		Files:        files,
+		ResponseType: types.RtnValueTypeNil,
+	}
+	if _, err := r.send(context.Background(), req).wait(); err != nil {
+		return fmt.Errorf("failed to send files because: %w", err)
+	}
+	return nil
+}
+
+// logStderr logs the stderr of the process line by line, up to MaxLogSize bytes.
+func (r *ProcRunner) logStderr() {
+	logged := 0
+	truncated := false
+	traceID := ""
+	scanner := bufio.NewScanner(r.stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if id, ok := strings.CutPrefix(line, types.TraceLogPrefix); ok {
+			// the lines that follow are logged during the request of the trace.
+			traceID = id
+			continue
+		}
+		if strings.Contains(line, "Fatal JavaScript out of memory") {
+			r.oom.Store(true)
+		}
+		if r.limits.MaxLogSize > 0 && logged+len(line) > r.limits.MaxLogSize {
+			if !truncated {
+				truncated = true
+				log.Debug().Msgf("v8 stderr exceeds %d bytes, truncated", r.limits.MaxLogSize)
+			}
+			continue
+		}
+		logged += len(line)
+		event := log.Debug()
+		if traceID != "" {
+			event = event.Str("trace_id", traceID)
+		}
+		event.Msgf("v8 stderr: %s", line)
+	}
+	// Check for errors in scanning
+	if err := scanner.Err(); err != nil {
+		log.Error().Err(err).Msg("error reading stderr")
+		// keep draining until the process exits
+		_, _ = io.Copy(io.Discard, r.stderr)
+	}
+}
+
+// IsClosed reports whether the runner has been closed, either by Close, by a timeout,
+// or because its process has exited on its own (e.g. a crash or a V8 abort).
 func (r *ProcRunner) IsClosed() bool {
 	return r.closed.Load()
 }
@@ -128,82 +471,587 @@ func (r *ProcRunner) Close() {
 
 // RunCodeJSON runs the given code and returns the JSON result.
 // There are multiple possible outcomes:
-//  1. The process is killed by the runner because of timeout.
-//     In this case, RunCodeJSON will return ErrorTimeout, and the runner will be closed.
-//  2. The process is killed by the runner because of memory limit.
-//     In this case, RunCodeJSON will return ErrorKilled, but the runner will not be closed.
-//     Subsequent calls to RunCodeJSON will return other Errors like broken pipe.
+//  1. The deadline of ctx is exceeded.
+//     In this case, the process terminates the script and RunCodeJSON will return ErrorTimeout,
+//     but the runner stays usable, with the state left by the terminated script.
+//     If the process does not respond within GracePeriodOption after the deadline, or if ctx
+//     is canceled, the process is killed instead, and the runner will be closed.
+//  2. The script exceeds the memory limit.
+//     In this case, the process terminates the script and RunCodeJSON will return ErrOutOfMemory.
+//     The runner stays usable, but the process is reset and loses the state of the previous
+//     requests. If the script allocates faster than the process can terminate it, V8 aborts the
+//     process: RunCodeJSON will return an error that is both ErrorKilled and ErrOutOfMemory,
+//     and the runner is closed once the process has exited. Subsequent calls to RunCodeJSON
+//     will return ErrorClosed.
 //  3. Successful execution.
 //     a. If the process returns a valid JSON, RunCodeJSON will return the JSON.
 //     b. If the process returns an error, RunCodeJSON will return the error.
-func (r *ProcRunner) RunCodeJSON(ctx context.Context, code string) (string, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+//  4. The execution exceeds the CPU budget set by WithCPUBudget.
+//     In this case, the script is terminated and RunCodeJSON will return ErrCPUBudgetExceeded,
+//     but the process is not killed and the runner stays usable.
+func (r *ProcRunner) RunCodeJSON(ctx context.Context, code string, opts ...RequestOption) (string, error) {
+	return r.RunCodeAsync(ctx, code, opts...).Get()
+}
 
-	// don't run if closed
-	if r.IsClosed() {
-		return "", ErrorClosed
+// RunCodeJSONWithStats is RunCodeJSON, and also returns the execution stats of the request,
+// e.g. to bill tenants or to catch slow scripts. The stats are returned with the error of
+// a script that failed too, they are zero if the request was not handled by the process,
+// e.g. if it was killed.
+func (r *ProcRunner) RunCodeJSONWithStats(
+	ctx context.Context,
+	code string,
+	opts ...RequestOption,
+) (string, types.ExecStats, error) {
+	opts = append(opts[:len(opts):len(opts)], func(req *types.RunCodeRequest) {
+		req.Stats = true
+	})
+	res, err := r.RunCodeAsync(ctx, code, opts...).c.wait()
+	var stats types.ExecStats
+	if res.Stats != nil {
+		stats = *res.Stats
 	}
+	if err != nil {
+		return "", stats, err
+	}
+	return *res.Result, stats, nil
+}
 
-	r.seq++
+// RunCodeAsync sends the given code to the process and returns immediately with a
+// Future of the JSON result, so that the caller can queue the next request while
+// the process is still busy. Requests are executed in the order they are submitted.
+// If the deadline of ctx is exceeded, the Future resolves to ErrorTimeout like in RunCodeJSON.
+// When the process is killed instead, every other pending request resolves to ErrorKilled.
+func (r *ProcRunner) RunCodeAsync(ctx context.Context, code string, opts ...RequestOption) *Future {
+	req := types.RunCodeRequest{
+		Code:         code,
+		ResponseType: types.RtnValueTypeJSON,
+	}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	return &Future{c: r.send(ctx, req)}
+}
 
-	vals := make(chan *string, 1)
-	errs := make(chan error, 1)
+// ScriptHandle refers to a script compiled in the process by Compile.
+type ScriptHandle struct {
+	name string
+}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+// Name returns the name the script was compiled under.
+func (h ScriptHandle) Name() string {
+	return h.name
+}
+
+// Compile sends code to the process to be compiled without running it, and registers it
+// under name, replacing the script previously compiled under the same name. It returns
+// the syntax errors of code, so that they are found before the script is first run.
+// The script is then run by Run, without sending its source again.
+func (r *ProcRunner) Compile(ctx context.Context, name, code string) (ScriptHandle, error) {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypeCompile,
+		Name:         name,
+		Code:         code,
+		ResponseType: types.RtnValueTypeNil,
+	}
+	if _, err := r.send(ctx, req).wait(); err != nil {
+		return ScriptHandle{}, err
+	}
+	return ScriptHandle{name: name}, nil
+}
 
+// Run runs the script compiled by Compile, and returns the JSON result.
+// The outcomes are the same as RunCodeJSON. It fails with ErrUnknownScript
+// if the script was not compiled by this runner.
+func (r *ProcRunner) Run(ctx context.Context, handle ScriptHandle, opts ...RequestOption) (string, error) {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypeRunCompiled,
+		Name:         handle.name,
+		ResponseType: types.RtnValueTypeJSON,
+	}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	return (&Future{c: r.send(ctx, req)}).Get()
+}
+
+// RegisterModules sends the ES modules of sources to the process, by specifier, replacing
+// the modules registered before under the same specifiers. Modules import each other by
+// specifier, and a relative specifier like "./util.js" is resolved against the specifier
+// of the importing module. The modules are evaluated by V8 when they are first imported,
+// see runner.Runner.RegisterModules.
+func (r *ProcRunner) RegisterModules(ctx context.Context, sources map[string]string) error {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypeModules,
+		Modules:      sources,
+		ResponseType: types.RtnValueTypeNil,
+	}
+	_, err := r.send(ctx, req).wait()
+	return err
+}
+
+// CallModule imports the module registered as specifier, calls the function it exports
+// as name ("default" for the default export) with the JSON encoded args, and returns the
+// JSON result. The outcomes are the same as RunCodeJSON. It fails with ErrModuleNotFound
+// if the module, or a module it imports, is not registered.
+func (r *ProcRunner) CallModule(
+	ctx context.Context,
+	specifier, name string,
+	args []string,
+	opts ...RequestOption,
+) (string, error) {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypeCallModule,
+		Name:         specifier,
+		Code:         name,
+		Inputs:       args,
+		ResponseType: types.RtnValueTypeJSON,
+	}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	return (&Future{c: r.send(ctx, req)}).Get()
+}
+
+// MapResult is the result of calling the function of MapJSON with one input.
+type MapResult struct {
+	Result string
+	Err    error
+}
+
+// MapJSON evaluates fn to a function, e.g. the name of a function defined by a previous
+// request, and calls it once with each of the JSON encoded inputs, all in one round-trip.
+// The results are in the order of inputs. A failing item does not fail the others, its
+// error is in MapResult.Err, use WithItemTimeout and WithTimeout to bound the items.
+// The error is non-nil if the whole request failed, with the same outcomes as RunCodeJSON.
+func (r *ProcRunner) MapJSON(
+	ctx context.Context,
+	fn string,
+	inputs []string,
+	opts ...RequestOption,
+) ([]MapResult, error) {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypeMap,
+		Code:         fn,
+		ResponseType: types.RtnValueTypeJSON,
+		Inputs:       inputs,
+	}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	res, err := r.send(ctx, req).wait()
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Items) != len(inputs) {
+		// should be impossible to reach here
+		return nil, fmt.Errorf("unexpected number of items: %d", len(res.Items))
+	}
+	results := make([]MapResult, len(res.Items))
+	for i, item := range res.Items {
+		switch {
+		case item.Error != nil:
+			results[i].Err = codeError(*item.Error, item.ErrorCode)
+		case item.Result != nil:
+			results[i].Result = *item.Result
+		}
+	}
+	return results, nil
+}
+
+// RunCodeStream runs the given code, which must return an iterable such as an array or
+// a generator, and yields the JSON of each of its values as soon as it is produced.
+// It is meant for outputs too large to be returned by RunCodeJSON at once.
+// The stream stops with ErrStreamTooLarge once the total size of the values exceeds
+// MaxStreamSizeOption. Other errors are the same as RunCodeJSON, and are yielded last.
+// The request is sent when the iteration starts, and it is safe to stop early.
+func (r *ProcRunner) RunCodeStream(
+	ctx context.Context,
+	code string,
+	opts ...RequestOption,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
 		req := types.RunCodeRequest{
-			ID:           fmt.Sprintf("%d", r.seq),
-			Code:         code,
-			ResponseType: types.RtnValueTypeJSON,
+			Code:          code,
+			ResponseType:  types.RtnValueTypeStream,
+			MaxStreamSize: r.maxStreamSize,
 		}
-		err := r.encoder.Encode(req)
-		if err != nil {
-			errs <- err
-			return
+		for _, opt := range opts {
+			opt(&req)
 		}
+		c := r.send(ctx, req)
+		total := 0
+		for {
+			select {
+			case res := <-c.items:
+				total += len(*res.Result)
+				if r.maxStreamSize > 0 && total > r.maxStreamSize {
+					// the process does not respect the limit.
+					go c.drain()
+					r.metrics.IncCounter(MetricKills)
+					r.Close()
+					yield("", fmt.Errorf("%w: exceeds %d bytes", ErrStreamTooLarge, r.maxStreamSize))
+					return
+				}
+				if !yield(*res.Result, nil) {
+					go c.drain()
+					return
+				}
+			case <-c.done:
+				if _, err := c.wait(); err != nil {
+					yield("", err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// send assigns an ID to req and writes it to the process. The returned call is
+// resolved by the reader once the response is received.
+func (r *ProcRunner) send(ctx context.Context, req types.RunCodeRequest) *call {
+	return r.sendIf(ctx, req, false)
+}
+
+// sendIf is send, but if idleOnly is set, the call fails with errNotIdle instead of being
+// sent unless the runner is idle, which is checked atomically with sending it.
+func (r *ProcRunner) sendIf(ctx context.Context, req types.RunCodeRequest, idleOnly bool) *call {
+	c := &call{done: make(chan struct{})}
+	if req.ResponseType == types.RtnValueTypeStream {
+		c.items = make(chan types.RunCodeResponse)
+	}
+	if r.tracer != nil {
+		c.trace = startTrace(ctx, r.tracer, req)
+		req.TraceID = c.trace.span.TraceID()
+		defer c.trace.done()
+	}
+	if r.limits.MaxCodeSize > 0 && req.CodeSize() > r.limits.MaxCodeSize {
+		c.resolve(types.RunCodeResponse{}, fmt.Errorf("%w: %d bytes exceeds %d bytes",
+			ErrCodeTooLarge, req.CodeSize(), r.limits.MaxCodeSize))
+		return c
+	}
+
+	r.mu.Lock()
+	if idleOnly && !r.idleLocked() {
+		r.mu.Unlock()
+		c.resolve(types.RunCodeResponse{}, errNotIdle)
+		return c
+	}
+	// don't run if closed
+	if r.IsClosed() {
+		r.mu.Unlock()
+		c.resolve(types.RunCodeResponse{}, ErrorClosed)
+		return c
+	}
+	// the pool is shutting down
+	if r.drained != nil {
+		r.mu.Unlock()
+		c.resolve(types.RunCodeResponse{}, fmt.Errorf("%w: %w", ErrorClosed, ErrPoolShutdown))
+		return c
+	}
+	// the process has exited but Wait() has not returned yet
+	if r.readErr != nil {
+		r.mu.Unlock()
+		c.resolve(types.RunCodeResponse{}, r.readErr)
+		return c
+	}
+	r.seq++
+	c.id = fmt.Sprintf("%d", r.seq)
+	if c.ping = req.Type == types.ReqTypePing; !c.ping {
+		r.executions++
+		r.lastActive = time.Now()
+	}
+	c.maxFrame = r.limits.frameSize(len(req.Inputs))
+	// the process cannot terminate a ping, it is killed on the deadline.
+	if deadline, ok := ctx.Deadline(); ok && req.Type != types.ReqTypePing {
+		req.Deadline = deadline
+	}
+	r.track(c) // before the AfterFunc, which may resolve c right away
+	c.stop = context.AfterFunc(ctx, func() {
+		if !req.Deadline.IsZero() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// the process terminates the script on the deadline by itself,
+			// it is killed only if it does not respond in time.
+			timer := time.NewTimer(r.gracePeriod)
+			defer timer.Stop()
+			select {
+			case <-c.done:
+				return
+			case <-timer.C:
+			}
+		}
+		c.timedOut.Store(true)
+		// Close() kills the process, the reader will then see an EOF
+		// and resolve every other pending call.
+		if !r.IsClosed() {
+			r.metrics.IncCounter(MetricKills)
+		}
+		r.Close()
+		c.resolve(types.RunCodeResponse{}, ErrorTimeout)
+	})
+	r.pending[c.id] = c
+	r.order = append(r.order, c)
+	r.mu.Unlock()
+
+	req.ID = c.id
+	r.writeMu.Lock()
+	start, written := time.Now(), r.written.n
+	err := r.encoder.Encode(req)
+	if c.trace != nil {
+		c.trace.wrote(c.id, start, time.Now(), r.written.n-written)
+	}
+	r.writeMu.Unlock()
+	if err != nil {
+		if c := r.popPending(c.id); c != nil {
+			c.finish(types.RunCodeResponse{}, err)
+		}
+	}
+	return c
+}
+
+func (r *ProcRunner) getPending(id string) *call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending[id]
+}
+
+// popOldest pops the pending call sent first, i.e. the one being executed.
+func (r *ProcRunner) popOldest() *call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	oldest := r.oldestLocked()
+	if oldest != nil {
+		r.removeLocked(oldest)
+	}
+	return oldest
+}
+
+// nextFrameSize is the maximum size of the next message, which responds to the oldest call.
+func (r *ProcRunner) nextFrameSize() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if oldest := r.oldestLocked(); oldest != nil {
+		return oldest.maxFrame
+	}
+	return r.limits.frameSize(0)
+}
 
+func (r *ProcRunner) oldestLocked() *call {
+	for len(r.order) > 0 {
+		c := r.order[0]
+		if r.pending[c.id] == c {
+			return c
+		}
+		r.order[0] = nil
+		r.order = r.order[1:]
+	}
+	return nil
+}
+
+func (r *ProcRunner) popPending(id string) *call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.pending[id]
+	if !ok {
+		return nil
+	}
+	r.removeLocked(c)
+	return c
+}
+
+// removeLocked removes c from the pending calls once it is responded to.
+func (r *ProcRunner) removeLocked(c *call) {
+	delete(r.pending, c.id)
+	if !c.ping {
+		r.lastActive = time.Now()
+	}
+	r.checkDrainedLocked()
+}
+
+// drain stops accepting requests, which fail with ErrorClosed and ErrPoolShutdown, and
+// returns a channel closed once the pending requests are responded to.
+func (r *ProcRunner) drain() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.drained == nil {
+		r.drained = make(chan struct{})
+		r.checkDrainedLocked()
+	}
+	return r.drained
+}
+
+func (r *ProcRunner) checkDrainedLocked() {
+	if r.drained == nil || len(r.pending) > 0 {
+		return
+	}
+	select {
+	case <-r.drained:
+	default:
+		close(r.drained)
+	}
+}
+
+// readLoop decodes responses until the process exits, and resolves the pending
+// call of each response. Once the process exits, every remaining call is failed.
+func (r *ProcRunner) readLoop() {
+	for {
 		var res types.RunCodeResponse
-		err = r.decoder.Decode(&res)
+		r.timer.reset()
+		start := time.Now()
+		err := r.decoder.Decode(&res)
+		received, decoded := r.timer.firstRead(start), time.Now()
 		if err != nil {
-			// error is EOF when the process is killed
-			if err == io.EOF {
-				errs <- ErrorKilled
-				return
+			// error is EOF (or the pipe is already closed) when the process is killed
+			if err == io.EOF || errors.Is(err, os.ErrClosed) {
+				err = ErrorKilled
+				if !r.IsClosed() {
+					r.metrics.IncCounter(MetricCrashes)
+					// recorded before the process is reaped, which closes the runner.
+					if r.healthCheck != nil {
+						r.retire(RetireUnhealthy)
+						r.metrics.IncCounter(MetricHealthCheckFailures)
+					}
+				}
+				// the process has exited, stderr tells if V8 aborted it.
+				<-r.stderrDone
+				if r.oom.Load() {
+					err = fmt.Errorf("%w: %w", ErrorKilled, ErrOutOfMemory)
+				}
+				if r.rssExceeded.Load() {
+					err = fmt.Errorf("%w: %w", ErrorKilled, ErrRSSLimitExceeded)
+				}
+			}
+			// the process does not respect the limit, and the stream cannot be
+			// decoded any further. It fails the request being executed only.
+			if errors.Is(err, ErrResultTooLarge) {
+				r.metrics.IncCounter(MetricKills)
+				r.closeFn()
+				if c := r.popOldest(); c != nil {
+					c.finish(types.RunCodeResponse{}, err)
+				}
+				err = ErrorKilled
 			}
-			errs <- err
+			r.failPending(err)
 			return
 		}
-		if res.Error != nil {
-			errs <- fmt.Errorf("%s", *res.Error)
-			return
+		if res.More {
+			c := r.getPending(res.ID)
+			if c == nil || c.items == nil {
+				// should be impossible to reach here
+				log.Error().Msgf("unexpected stream id: %s", res.ID)
+				continue
+			}
+			if c.trace != nil {
+				c.trace.receive(res, received, decoded)
+			}
+			c.items <- res
+			continue
+		}
+		if res.ScriptCache != nil {
+			r.cacheMu.Lock()
+			r.cacheStats = r.cacheStats.Add(*res.ScriptCache)
+			r.cacheMu.Unlock()
 		}
-		if res.ID != req.ID {
+		c := r.popPending(res.ID)
+		if c == nil {
 			// should be impossible to reach here
-			errs <- fmt.Errorf("unexpected id: %s", res.ID)
-			return
+			log.Error().Msgf("unexpected id: %s", res.ID)
+			continue
 		}
-		vals <- res.Result
-	}()
+		if c.trace != nil {
+			c.trace.receive(res, received, decoded)
+		}
+		c.finish(res, nil)
+		r.startOldest()
+	}
+}
 
-	select {
-	case val := <-vals:
-		return *val, nil
-	case err := <-errs:
-		return "", err
-	case <-ctx.Done():
-		r.Close()
-		// prevent goroutine leak
-		// Close() would have killed the process and should
-		// send an EOF to the decoder.
-		wg.Wait()
-		return "", ErrorTimeout
+// track reports the metrics of c, which is about to be added to the pending calls,
+// and when it starts to its trace.
+// It must be called with mu held.
+func (r *ProcRunner) track(c *call) {
+	c.sent = time.Now()
+	r.metrics.AddGauge(MetricInFlight, 1)
+	if len(r.pending) == 0 {
+		c.started = true
+		r.metrics.Observe(MetricQueueWait, 0)
+		if c.trace != nil {
+			c.trace.start(c.sent)
+		}
+	}
+	c.onResolve = func(res types.RunCodeResponse, err error) {
+		r.metrics.AddGauge(MetricInFlight, -1)
+		r.metrics.IncCounter(MetricRequests)
+		r.metrics.Observe(MetricRequestDuration, time.Since(c.sent).Seconds())
+		if err == nil && res.Error != nil {
+			err = responseError(*res.Error, res.ErrorCode)
+		}
+		if err != nil {
+			r.metrics.IncCounter(MetricRequestErrors)
+		}
+		if errors.Is(err, ErrorTimeout) {
+			r.metrics.IncCounter(MetricTimeouts)
+		}
 	}
 }
 
+// startOldest reports the queue wait of the oldest pending call, which starts once the
+// calls sent before it are responded to.
+func (r *ProcRunner) startOldest() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c := r.oldestLocked(); c != nil && !c.started {
+		c.started = true
+		r.metrics.Observe(MetricQueueWait, time.Since(c.sent).Seconds())
+		if c.trace != nil {
+			c.trace.start(time.Now())
+		}
+	}
+}
+
+func (r *ProcRunner) failPending(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readErr = err
+	for id, c := range r.pending {
+		c.finish(types.RunCodeResponse{}, err)
+		delete(r.pending, id)
+	}
+	r.order = nil
+	r.checkDrainedLocked()
+}
+
+// Ping checks that the process responds, once the requests sent before are responded to.
+// A process that does not respond before ctx is done is killed, like a request, and Ping
+// fails with ErrorTimeout. See HealthCheckOption.
+func (r *ProcRunner) Ping(ctx context.Context) error {
+	return r.ping(ctx, false)
+}
+
+// ping is Ping, but if idleOnly is set, it fails with errNotIdle unless the runner is idle
+// when the ping is sent, so that the ping does not wait for a request.
+func (r *ProcRunner) ping(ctx context.Context, idleOnly bool) error {
+	req := types.RunCodeRequest{
+		Type:         types.ReqTypePing,
+		ResponseType: types.RtnValueTypeNil,
+	}
+	_, err := r.sendIf(ctx, req, idleOnly).wait()
+	return err
+}
+
+// idleLocked reports whether the runner has no pending request, its process is running
+// and it is not drained.
+func (r *ProcRunner) idleLocked() bool {
+	return len(r.pending) == 0 && r.readErr == nil && r.drained == nil && !r.IsClosed()
+}
+
+// ScriptCacheStats returns how the scripts of the requests have been compiled so far:
+// found in the cache of the process, compiled from the code cache on disk, or compiled
+// from source. See ScriptCacheOption.
+func (r *ProcRunner) ScriptCacheStats() types.ScriptCacheStats {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	return r.cacheStats
+}
+
 // AddPostCloseFn adds a function to be called after the runner is closed.
 // NOTE: NOT concurrency safe.
 func (r *ProcRunner) AddPostCloseFn(f func()) {
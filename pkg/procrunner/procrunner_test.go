@@ -2,10 +2,18 @@ package procrunner
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/stumble/v8runner/pkg/types"
 )
 
 // ProcRunnerTestSuite is the test suite for ProcRunner.
@@ -36,30 +44,76 @@ func (suite *ProcRunnerTestSuite) TestTimeout() {
 	suite.Require().NoError(err)
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
-	res, err := runner.RunCodeJSON(ctx, "while(true){}")
+	res, err := runner.RunCodeJSON(ctx, "let n = 0; while(true){ n++; }")
 	suite.Equal(ErrorTimeout, err)
 	suite.Equal("", res)
-	// timeout close the runner
-	suite.True(runner.IsClosed())
+	// the process terminates the script, and keeps its state
+	suite.False(runner.IsClosed())
+	res, err = runner.RunCodeJSON(context.Background(), "n > 0")
+	suite.NoError(err)
+	suite.Equal("true", res)
+	runner.Close()
 	// safe to close twice
 	runner.Close()
 }
 
+func (suite *ProcRunnerTestSuite) TestTimeoutUnresponsive() {
+	runner, err := NewProcRunner("expression.js", 16, GracePeriodOption{Duration: 200 * time.Millisecond})
+	suite.Require().NoError(err)
+	// the process does not respond, e.g. it is stuck outside of the script
+	suite.Require().NoError(runner.cmd.Process.Signal(syscall.SIGSTOP))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, err := runner.RunCodeJSON(ctx, "1+1")
+	suite.Equal(ErrorTimeout, err)
+	suite.Equal("", res)
+	suite.Less(time.Since(start), 2*time.Second)
+	// it is killed after the grace period, which closes the runner
+	suite.True(runner.IsClosed())
+	runner.Close()
+}
+
 func (suite *ProcRunnerTestSuite) TestMemoryLimit() {
 	runner, err := NewProcRunner("expression.js", 4)
 	suite.Require().NoError(err)
+	defer runner.Close()
+	_, err = runner.RunCodeJSON(context.Background(), "var n = 1;")
+	suite.Require().NoError(err)
 	res, err := runner.RunCodeJSON(context.Background(), `
   let memoryHog = [];
   while (true) {
       memoryHog.push(new Array(1024 * 1024).fill('X')); // Allocate 1MB chunks of memory
   }
 `)
-	suite.Equal(ErrorKilled, err)
+	suite.ErrorIs(err, ErrOutOfMemory)
+	suite.NotErrorIs(err, ErrorKilled)
 	suite.Equal("", res)
-	// memory limit does not close the runner
+	// the process is reset instead of being killed
 	suite.False(runner.IsClosed())
+	res, err = runner.RunCodeJSON(context.Background(), "typeof n")
+	suite.NoError(err)
+	suite.Equal(`"undefined"`, res)
+}
+
+func (suite *ProcRunnerTestSuite) TestMemoryLimitAbort() {
+	runner, err := NewProcRunner("expression.js", 4, HeapHeadroomOption{MB: 0})
+	suite.Require().NoError(err)
+	defer runner.Close()
+	res, err := runner.RunCodeJSON(context.Background(), `
+  let memoryHog = [];
+  while (true) {
+      memoryHog.push(new Array(1024 * 1024).fill('X')); // Allocate 1MB chunks of memory
+  }
+`)
+	// V8 aborts the process
+	suite.ErrorIs(err, ErrorKilled)
+	suite.ErrorIs(err, ErrOutOfMemory)
+	suite.Equal("", res)
+	// the runner is closed once the aborted process has exited
+	suite.Eventually(runner.IsClosed, time.Second, 10*time.Millisecond)
 	res2, err2 := runner.RunCodeJSON(context.Background(), "1+1")
-	suite.NotNil(err2)
+	suite.ErrorIs(err2, ErrorClosed)
 	suite.Equal("", res2)
 }
 
@@ -87,3 +141,416 @@ func (suite *ProcRunnerTestSuite) TestThrow() {
 	// safe to close twice
 	runner.Close()
 }
+
+func (suite *ProcRunnerTestSuite) TestRunCodeAsync() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.RunCodeJSON(context.Background(), "const f = (x) => x * 2;")
+	suite.Require().NoError(err)
+	futures := make([]*Future, 0, 100)
+	for i := 0; i < 100; i++ {
+		futures = append(futures, runner.RunCodeAsync(context.Background(), fmt.Sprintf("f(%d)", i)))
+	}
+	for i, f := range futures {
+		res, err := f.Get()
+		suite.NoError(err)
+		suite.Equal(fmt.Sprintf("%d", i*2), res)
+	}
+}
+
+func (suite *ProcRunnerTestSuite) TestRunCodeAsyncTimeout() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	slow := runner.RunCodeAsync(ctx, "while(true){}")
+	queued := runner.RunCodeAsync(context.Background(), "1+1")
+	_, err = slow.Get()
+	suite.Equal(ErrorTimeout, err)
+	// requests queued behind the timed out one are executed next
+	res, err := queued.Get()
+	suite.NoError(err)
+	suite.Equal("2", res)
+}
+
+func (suite *ProcRunnerTestSuite) TestRunCodeAsyncCancel() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := runner.RunCodeAsync(ctx, "while(true){}")
+	queued := runner.RunCodeAsync(context.Background(), "1+1")
+	time.AfterFunc(200*time.Millisecond, cancel)
+	_, err = slow.Get()
+	suite.Equal(ErrorTimeout, err)
+	// a cancel kills the process, requests queued behind are lost with it
+	_, err = queued.Get()
+	suite.Equal(ErrorKilled, err)
+	suite.True(runner.IsClosed())
+	_, err = runner.RunCodeAsync(context.Background(), "1+1").Get()
+	suite.Equal(ErrorClosed, err)
+}
+
+func (suite *ProcRunnerTestSuite) TestMapJSON() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.RunCodeJSON(context.Background(), `
+function f(v) {
+  if (v.throw) { throw "bad record"; }
+  if (v.loop) { while(true){} }
+  return {a: v.X, b: v.Y};
+}`)
+	suite.Require().NoError(err)
+	inputs := []string{`{"X":1,"Y":2}`, `{"throw":true}`, `{"loop":true}`, `{"X":3,"Y":4}`}
+	results, err := runner.MapJSON(context.Background(), "f", inputs, WithItemTimeout(100*time.Millisecond))
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 4)
+	suite.Equal(MapResult{Result: `{"a":1,"b":2}`}, results[0])
+	suite.Equal("failed to call function because: bad record", results[1].Err.Error())
+	suite.ErrorContains(results[2].Err, "timeout")
+	suite.Equal(MapResult{Result: `{"a":3,"b":4}`}, results[3])
+
+	// the overall timeout skips the remaining items
+	results, err = runner.MapJSON(context.Background(), "f", inputs[2:], WithTimeout(100*time.Millisecond))
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 2)
+	suite.ErrorContains(results[0].Err, "timeout")
+	suite.ErrorContains(results[1].Err, "item not started")
+
+	_, err = runner.MapJSON(context.Background(), "notDefined", inputs)
+	suite.ErrorContains(err, "ReferenceError")
+	suite.False(runner.IsClosed())
+}
+
+func (suite *ProcRunnerTestSuite) TestRunCodeStream() {
+	runner, err := NewProcRunner("expression.js", 16, MaxStreamSizeOption{Bytes: 1024})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.RunCodeJSON(context.Background(), `
+function* records(n) {
+  for (let i = 0; i < n; i++) { yield {i}; }
+}`)
+	suite.Require().NoError(err)
+	var items []string
+	for item, err := range runner.RunCodeStream(context.Background(), "records(3)") {
+		suite.Require().NoError(err)
+		items = append(items, item)
+	}
+	suite.Equal([]string{`{"i":0}`, `{"i":1}`, `{"i":2}`}, items)
+
+	// stop early, the rest of the stream is discarded
+	for item, err := range runner.RunCodeStream(context.Background(), "records(1000)") {
+		suite.Require().NoError(err)
+		suite.Equal(`{"i":0}`, item)
+		break
+	}
+	res, err := runner.RunCodeJSON(context.Background(), "1+1")
+	suite.NoError(err)
+	suite.Equal("2", res)
+
+	// exceeds MaxStreamSizeOption
+	var lastErr error
+	count := 0
+	for _, err := range runner.RunCodeStream(context.Background(), "records(1000)") {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		count++
+	}
+	suite.ErrorIs(lastErr, ErrStreamTooLarge)
+	suite.Less(count, 1000)
+	suite.False(runner.IsClosed())
+}
+
+func (suite *ProcRunnerTestSuite) TestLimits() {
+	runner, err := NewProcRunner("expression.js", 16, LimitsOption{
+		MaxCodeSize:   100,
+		MaxResultSize: 100,
+		MaxLogSize:    31,
+	})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.RunCodeJSON(context.Background(), strings.Repeat(" ", 101)+"1")
+	suite.ErrorIs(err, ErrCodeTooLarge)
+
+	_, err = runner.RunCodeJSON(context.Background(), "'x'.repeat(1000)")
+	suite.ErrorIs(err, ErrResultTooLarge)
+
+	results, err := runner.MapJSON(context.Background(), "(n) => 'x'.repeat(n)", []string{"10", "100"})
+	suite.ErrorIs(err, ErrResultTooLarge)
+	suite.Nil(results)
+
+	_, err = runner.RunCodeJSON(context.Background(), "throw '"+strings.Repeat("e", 50)+"'")
+	suite.Equal("failed to run script because: e", err.Error())
+
+	res, err := runner.RunCodeJSON(context.Background(), "1+1")
+	suite.NoError(err)
+	suite.Equal("2", res)
+}
+
+func (suite *ProcRunnerTestSuite) TestCPUBudget() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	res, err := runner.RunCodeJSON(context.Background(), "while(true){}", WithCPUBudget(100*time.Millisecond))
+	suite.ErrorIs(err, ErrCPUBudgetExceeded)
+	suite.Equal("cpu budget exceeded: 100ms", err.Error())
+	suite.Equal("", res)
+	suite.False(runner.IsClosed())
+
+	// the budget is shared by all the items: the first two use a fraction of it, the third
+	// loops until it is exhausted
+	_, err = runner.RunCodeJSON(context.Background(), "function spin(n) { if (n < 0) { while (true) {} } let s = 0; for (let i = 0; i < n; i++) { s += i; } return n; }")
+	suite.Require().NoError(err)
+	results, err := runner.MapJSON(context.Background(), "spin", []string{"1000", "1000", "-1"},
+		WithCPUBudget(300*time.Millisecond))
+	suite.Require().NoError(err)
+	suite.Equal(MapResult{Result: "1000"}, results[0])
+	suite.Equal(MapResult{Result: "1000"}, results[1])
+	suite.ErrorIs(results[2].Err, ErrCPUBudgetExceeded)
+
+	res, err = runner.RunCodeJSON(context.Background(), "1+1", WithCPUBudget(100*time.Millisecond))
+	suite.NoError(err)
+	suite.Equal("2", res)
+}
+
+func (suite *ProcRunnerTestSuite) TestSnapshot() {
+	dir := suite.T().TempDir()
+	lib := filepath.Join(dir, "lib.js")
+	snapshot := filepath.Join(dir, "lib.snap")
+	suite.Require().NoError(os.WriteFile(lib, []byte("const lib = { double: (x) => x * 2 };"), 0o600))
+	out, err := exec.Command("v8runner", "snapshot", "--bootstrap", lib, "-o", snapshot).CombinedOutput()
+	suite.Require().NoError(err, string(out))
+
+	runner, err := NewProcRunner("expression.js", 16, SnapshotOption{Path: snapshot})
+	suite.Require().NoError(err)
+	defer runner.Close()
+	res, err := runner.RunCodeJSON(context.Background(), "lib.double(21)")
+	suite.NoError(err)
+	suite.Equal("42", res)
+}
+
+func (suite *ProcRunnerTestSuite) TestScriptCache() {
+	dir := suite.T().TempDir()
+	runner, err := NewProcRunner("expression.js", 16, ScriptCacheOption{Size: 16, Dir: dir})
+	suite.Require().NoError(err)
+	defer runner.Close()
+	for i := 0; i < 3; i++ {
+		res, err := runner.RunCodeJSON(context.Background(), "[1, 2].map((x) => x * 2)")
+		suite.NoError(err)
+		suite.Equal("[2,4]", res)
+	}
+	suite.Equal(types.ScriptCacheStats{Hits: 2, Misses: 1}, runner.ScriptCacheStats())
+
+	// a new process skips parsing the script
+	other, err := NewProcRunner("expression.js", 16, ScriptCacheOption{Size: 16, Dir: dir})
+	suite.Require().NoError(err)
+	defer other.Close()
+	_, err = other.RunCodeJSON(context.Background(), "[1, 2].map((x) => x * 2)")
+	suite.NoError(err)
+	suite.Equal(types.ScriptCacheStats{DiskHits: 1}, other.ScriptCacheStats())
+}
+
+func (suite *ProcRunnerTestSuite) TestCompile() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.Compile(context.Background(), "broken", "let x = ;")
+	suite.ErrorContains(err, "SyntaxError")
+	_, err = runner.Run(context.Background(), ScriptHandle{name: "broken"})
+	suite.ErrorIs(err, ErrUnknownScript)
+
+	handle, err := runner.Compile(context.Background(), "rule", "({ total: [1, 2, 3].reduce((a, b) => a + b) })")
+	suite.Require().NoError(err)
+	suite.Equal("rule", handle.Name())
+	res, err := runner.Run(context.Background(), handle)
+	suite.NoError(err)
+	suite.Equal(`{"total":6}`, res)
+}
+
+func (suite *ProcRunnerTestSuite) TestModules() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	err = runner.RegisterModules(context.Background(), map[string]string{
+		"lib/util.js": `export const double = (x) => x * 2;`,
+		"rules.js": `
+			import { double } from "./lib/util.js";
+			export default function score(order) { return { score: double(order.amount) }; }
+			export function broken() { return missing(); }
+		`,
+		"missing.js": `import { x } from "./nowhere.js"; export default () => x;`,
+	})
+	suite.Require().NoError(err)
+	res, err := runner.CallModule(context.Background(), "rules.js", "default", []string{`{"amount": 21}`})
+	suite.NoError(err)
+	suite.Equal(`{"score":42}`, res)
+	_, err = runner.CallModule(context.Background(), "rules.js", "broken", nil)
+	suite.ErrorContains(err, "missing is not defined")
+	_, err = runner.CallModule(context.Background(), "nowhere.js", "default", nil)
+	suite.ErrorIs(err, ErrModuleNotFound)
+	_, err = runner.CallModule(context.Background(), "missing.js", "default", nil)
+	suite.ErrorIs(err, ErrModuleNotFound)
+	suite.EqualError(err, "module not found: nowhere.js")
+}
+
+func (suite *ProcRunnerTestSuite) TestFiles() {
+	runner, err := NewProcRunner("expression.js", 16, FilesOption{
+		FS: fstest.MapFS{
+			"lib/util.js":                 {Data: []byte(`exports.double = (x) => x * 2;`)},
+			"node_modules/dayjs/index.js": {Data: []byte(`module.exports = () => "today";`)},
+		},
+		Files: map[string]string{"rules.js": `module.exports = (o) => require("./lib/util").double(o.amount);`},
+	})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	res, err := runner.RunCodeJSON(context.Background(), `require("./rules")({amount: 21})`)
+	suite.NoError(err)
+	suite.Equal("42", res)
+	res, err = runner.RunCodeJSON(context.Background(), `require("dayjs")()`)
+	suite.NoError(err)
+	suite.Equal(`"today"`, res)
+	_, err = runner.RunCodeJSON(context.Background(), `require("fs")`)
+	suite.ErrorContains(err, "cannot find module 'fs'")
+}
+
+func (suite *ProcRunnerTestSuite) TestPolyfills() {
+	runner, err := NewProcRunner("expression.js", 16, PolyfillsOption{})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	res, err := runner.RunCodeJSON(context.Background(),
+		`[atob(btoa("ok")), new URL("/b?q=1", "https://example.com/a").searchParams.get("q"),
+		  new TextDecoder().decode(new TextEncoder().encode("€")), structuredClone({a: [1]}).a[0]]`)
+	suite.NoError(err)
+	suite.Equal(`["ok","1","€",1]`, res)
+}
+
+func (suite *ProcRunnerTestSuite) TestDeterministic() {
+	runner, err := NewProcRunner("expression.js", 16, DeterministicOption{})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	const code = `[Math.random(), Date.now(), new Date().getHours(), performance.now()]`
+	clock := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	first, err := runner.RunCodeJSON(context.Background(), code, WithSeed(7), WithClock(clock, time.Second))
+	suite.Require().NoError(err)
+	suite.Contains(first, `,1709209800000,12,2000]`)
+	again, err := runner.RunCodeJSON(context.Background(), code, WithSeed(7), WithClock(clock, time.Second))
+	suite.NoError(err)
+	suite.Equal(first, again)
+	res, err := runner.RunCodeJSON(context.Background(), `Date.now()`)
+	suite.NoError(err)
+	suite.Equal("0", res)
+
+	plain, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer plain.Close()
+	_, err = plain.RunCodeJSON(context.Background(), code, WithSeed(7))
+	suite.ErrorContains(err, "not deterministic")
+}
+
+func (suite *ProcRunnerTestSuite) TestLockdown() {
+	runner, err := NewProcRunner("expression.js", 16, LockdownOption{
+		NoCodeGeneration: true, NoWebAssembly: true, NoSharedMemory: true, FreezeIntrinsics: true,
+	})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.RunCodeJSON(context.Background(), `eval("1")`)
+	suite.ErrorContains(err, "Code generation from strings disallowed")
+	res, err := runner.RunCodeJSON(context.Background(),
+		`[typeof WebAssembly, typeof SharedArrayBuffer, typeof Atomics, Object.isFrozen(Array.prototype)]`)
+	suite.NoError(err)
+	suite.Equal(`["undefined","undefined","undefined",true]`, res)
+}
+
+func (suite *ProcRunnerTestSuite) TestRunCodeJSONWithStats() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	res, stats, err := runner.RunCodeJSONWithStats(context.Background(),
+		`globalThis.kept = Array.from({length: 100000}, (_, i) => ({i})); let x = 0; for (let i = 0; i < 1e7; i++) x += i; x`)
+	suite.Require().NoError(err)
+	suite.Equal("49999995000000", res)
+	suite.Greater(stats.WallTime, time.Duration(0))
+	suite.Greater(stats.CPUTime, time.Duration(0))
+	suite.LessOrEqual(stats.CPUTime, stats.WallTime)
+	suite.Greater(stats.CompileTime, time.Duration(0))
+	suite.Greater(stats.HeapUsedAfter, stats.HeapUsedBefore)
+	suite.GreaterOrEqual(stats.TotalHeapSize, stats.HeapUsedAfter)
+
+	// short-lived garbage is collected by the GCs counted in the stats.
+	_, stats, err = runner.RunCodeJSONWithStats(context.Background(),
+		`for (let i = 0; i < 1e6; i++) globalThis.last = {i, a: [i, i]}; 1`)
+	suite.Require().NoError(err)
+	suite.Greater(stats.GCCount, uint64(0))
+
+	// the stats of a failed script are returned too.
+	_, stats, err = runner.RunCodeJSONWithStats(context.Background(), `throw new Error("failed")`)
+	suite.ErrorContains(err, "failed")
+	suite.Greater(stats.WallTime, time.Duration(0))
+	suite.Greater(stats.HeapUsedBefore, uint64(0))
+}
+
+func (suite *ProcRunnerTestSuite) TestPing() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	suite.NoError(runner.Ping(context.Background()))
+
+	// a ping waits for the requests sent before it.
+	slow := runner.RunCodeAsync(context.Background(), "const end = Date.now() + 100; while (Date.now() < end) {}; 1")
+	suite.NoError(runner.Ping(context.Background()))
+	select {
+	case <-slow.Done():
+	default:
+		suite.Fail("ping responded before the request sent before it")
+	}
+
+	// a stopped process is killed once the ping times out.
+	suite.Require().NoError(runner.cmd.Process.Signal(syscall.SIGSTOP))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	suite.ErrorIs(runner.Ping(ctx), ErrorTimeout)
+	suite.True(runner.IsClosed())
+}
+
+func (suite *ProcRunnerTestSuite) TestHealthCheckBusy() {
+	// the pings time out before the requests end, so a ping sent behind a request
+	// would kill the process.
+	runner, err := NewProcRunner("expression.js", 16, HealthCheckOption{Interval: 5 * time.Millisecond, Timeout: 50 * time.Millisecond})
+	suite.Require().NoError(err)
+	defer runner.Close()
+	_, err = runner.RunCodeJSON(context.Background(), "function spin(ms) { const end = Date.now() + ms; while (Date.now() < end) {} return ms; }")
+	suite.Require().NoError(err)
+
+	// a health check ping is not sent once a request is pending.
+	slow := runner.RunCodeAsync(context.Background(), "spin(200)")
+	suite.ErrorIs(runner.ping(context.Background(), true), errNotIdle)
+	res, err := slow.Get()
+	suite.NoError(err)
+	suite.Equal("200", res)
+
+	// the requests are sent while the runner is idle, some of them right before a tick.
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Duration(i) * time.Millisecond)
+		res, err := runner.RunCodeJSON(context.Background(), "spin(100)")
+		suite.Require().NoError(err)
+		suite.Equal("100", res)
+	}
+	suite.False(runner.IsClosed())
+	suite.Equal(RetireReason(""), runner.RetireReason())
+}
@@ -0,0 +1,113 @@
+package procrunner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrProcStatsUnsupported = fmt.Errorf("process stats not supported")
+	ErrRSSLimitExceeded     = fmt.Errorf("rss limit exceeded")
+)
+
+// ProcStats are the resources used by the process of a runner, as reported by the OS.
+// Unlike the V8 heap stats, RSS includes the native memory of the process, e.g. the
+// contents of ArrayBuffers, the code of the compiled scripts and the memory of V8 itself.
+type ProcStats struct {
+	// RSS is the resident set size of the process, in bytes.
+	RSS uint64
+	// PeakRSS is the highest RSS of the process so far (VmHWM), in bytes.
+	PeakRSS uint64
+	// UserTicks and SystemTicks are the CPU time spent by the process in user and
+	// kernel mode, in clock ticks.
+	UserTicks   uint64
+	SystemTicks uint64
+	// CPUTime is the sum of UserTicks and SystemTicks.
+	CPUTime time.Duration
+}
+
+// Stats samples the resources used by the process from /proc. It fails with
+// ErrProcStatsUnsupported outside of linux, and with ErrorClosed once the runner is
+// closed, before the PID of its process can be reused by another process.
+func (r *ProcRunner) Stats() (ProcStats, error) {
+	// the process is reaped under mu, so the PID stays valid while reading /proc.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.IsClosed() || r.reaped {
+		return ProcStats{}, ErrorClosed
+	}
+	return readProcStats(r.cmd.Process.Pid)
+}
+
+// defaultRSSInterval is the interval of a MaxRSSOption without Interval.
+const defaultRSSInterval = time.Second
+
+// MaxRSSOption kills the process once its RSS exceeds MB, even if its V8 heap is within
+// its limit, closes the runner and replaces it. The pending requests fail with ErrorKilled
+// and ErrRSSLimitExceeded. The replacement runs the same file with the same max heap size
+// and options, in a new process without the state of the previous requests, see
+// ProcRunner.Replacement. In a ProcRunnerPool, it takes the slot of the killed runner.
+// The RSS is sampled every Interval, every second by default, so a process may exceed MB
+// until the next sample.
+type MaxRSSOption struct {
+	MB       uint
+	Interval time.Duration
+}
+
+func (o MaxRSSOption) apply(r *ProcRunner) {
+	r.maxRSS = &o
+}
+
+// limitRSS samples the RSS of the process until exited is closed, and kills the process
+// once it exceeds the limit.
+func (r *ProcRunner) limitRSS(exited <-chan struct{}) {
+	interval := r.maxRSS.Interval
+	if interval <= 0 {
+		interval = defaultRSSInterval
+	}
+	limit := uint64(r.maxRSS.MB) << 20
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+		stats, err := r.Stats()
+		if err != nil {
+			// the runner is closed, the process is exiting.
+			continue
+		}
+		if stats.RSS > limit && !r.IsClosed() {
+			log.Debug().Msgf("v8 rss of %d bytes exceeds %d MB, killed", stats.RSS, r.maxRSS.MB)
+			r.rssExceeded.Store(true)
+			r.retire(RetireRSSLimit)
+			r.metrics.IncCounter(MetricRSSKills)
+			// set before the kill, so that the pool knows the slot is taken over.
+			r.replacing.Store(true)
+			r.closeFn()
+			r.replace()
+			r.Close()
+			return
+		}
+	}
+}
+
+// replace spawns the replacement of a runner killed by MaxRSSOption.
+func (r *ProcRunner) replace() {
+	replacement, err := r.respawn()
+	if err != nil {
+		log.Debug().Err(err).Msg("v8 runner not replaced")
+		return
+	}
+	r.replacement.Store(replacement)
+}
+
+// Replacement returns the runner that replaced the runner once MaxRSSOption killed its
+// process, nil if it has not been replaced. The replacement must be closed after use.
+func (r *ProcRunner) Replacement() *ProcRunner {
+	return r.replacement.Load()
+}
@@ -0,0 +1,69 @@
+//go:build linux
+
+package procrunner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the CPU times of /proc/<pid>/stat, which is 100
+// on every architecture supported by Go.
+const clockTicks = 100
+
+func readProcStats(pid int) (ProcStats, error) {
+	var stats ProcStats
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return stats, err
+	}
+	// lines such as "VmRSS:	    2048 kB"
+	for _, line := range bytes.Split(status, []byte("\n")) {
+		key, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			continue
+		}
+		switch string(key) {
+		case "VmRSS":
+			stats.RSS, err = parseKB(value)
+		case "VmHWM":
+			stats.PeakRSS, err = parseKB(value)
+		}
+		if err != nil {
+			return stats, fmt.Errorf("invalid %s of /proc/%d/status: %w", key, pid, err)
+		}
+	}
+
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return stats, err
+	}
+	// "pid (comm) state ppid ...", comm may contain spaces and parentheses, so the fields
+	// are counted from the last parenthesis: state is the 3rd field, utime the 14th and
+	// stime the 15th.
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return stats, fmt.Errorf("invalid /proc/%d/stat", pid)
+	}
+	fields := bytes.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return stats, fmt.Errorf("invalid /proc/%d/stat", pid)
+	}
+	if stats.UserTicks, err = strconv.ParseUint(string(fields[11]), 10, 64); err != nil {
+		return stats, fmt.Errorf("invalid utime of /proc/%d/stat: %w", pid, err)
+	}
+	if stats.SystemTicks, err = strconv.ParseUint(string(fields[12]), 10, 64); err != nil {
+		return stats, fmt.Errorf("invalid stime of /proc/%d/stat: %w", pid, err)
+	}
+	stats.CPUTime = time.Duration(stats.UserTicks+stats.SystemTicks) * time.Second / clockTicks
+	return stats, nil
+}
+
+// parseKB parses a value such as "    2048 kB" to bytes.
+func parseKB(value []byte) (uint64, error) {
+	n, err := strconv.ParseUint(string(bytes.TrimSuffix(bytes.TrimSpace(value), []byte(" kB"))), 10, 64)
+	return n << 10, err
+}
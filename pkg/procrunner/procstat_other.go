@@ -0,0 +1,8 @@
+//go:build !linux
+
+package procrunner
+
+// readProcStats is not supported outside of linux, which disables MaxRSSOption.
+func readProcStats(int) (ProcStats, error) {
+	return ProcStats{}, ErrProcStatsUnsupported
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package procrunner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProcStatsTestSuite struct {
+	suite.Suite
+}
+
+func TestProcStatsTestSuite(t *testing.T) {
+	suite.Run(t, new(ProcStatsTestSuite))
+}
+
+func (suite *ProcStatsTestSuite) TestStats() {
+	runner, err := NewProcRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	before, err := runner.Stats()
+	suite.Require().NoError(err)
+	suite.Greater(before.RSS, uint64(0))
+	suite.GreaterOrEqual(before.PeakRSS, before.RSS)
+
+	// native memory is not limited by the V8 heap.
+	_, err = runner.RunCodeJSON(context.Background(), `
+		globalThis.kept = new Uint8Array(64 << 20).fill(1);
+		const end = Date.now() + 100; while (Date.now() < end) {}; 1`)
+	suite.Require().NoError(err)
+	after, err := runner.Stats()
+	suite.Require().NoError(err)
+	suite.Greater(after.RSS, before.RSS+(60<<20))
+	suite.GreaterOrEqual(after.PeakRSS, after.RSS)
+	suite.Greater(after.UserTicks+after.SystemTicks, before.UserTicks+before.SystemTicks)
+	suite.Equal(time.Duration(after.UserTicks+after.SystemTicks)*10*time.Millisecond, after.CPUTime)
+
+	runner.Close()
+	_, err = runner.Stats()
+	suite.Equal(ErrorClosed, err)
+}
+
+func (suite *ProcStatsTestSuite) TestMaxRSS() {
+	m := newRecorder()
+	pool := NewProcRunnerPool(1, MaxRSSOption{MB: 128, Interval: 10 * time.Millisecond},
+		MetricsOption{Metrics: m})
+	runner, err := pool.NewRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	res, err := runner.RunCodeJSON(context.Background(), `globalThis.kept = new Uint8Array(32 << 20).fill(1); 1`)
+	suite.NoError(err)
+	suite.Equal("1", res)
+
+	_, err = runner.RunCodeJSON(context.Background(), `
+		globalThis.more = new Uint8Array(256 << 20).fill(1);
+		while (true) {}`)
+	suite.ErrorIs(err, ErrorKilled)
+	suite.ErrorIs(err, ErrRSSLimitExceeded)
+	suite.True(runner.IsClosed())
+	suite.Equal(1, m.counter(MetricRSSKills))
+
+	// the runner is replaced in its slot, by a new process without the state of the runner.
+	suite.Eventually(func() bool { return runner.Replacement() != nil }, time.Second, 10*time.Millisecond)
+	replacement := runner.Replacement()
+	defer replacement.Close()
+	suite.False(replacement.IsClosed())
+	suite.Equal(1, pool.Running())
+	_, err = pool.NewRunner("expression.js", 16)
+	suite.ErrorIs(err, ErrMaxReached)
+	res, err = replacement.RunCodeJSON(context.Background(), "typeof kept")
+	suite.NoError(err)
+	suite.Equal(`"undefined"`, res)
+
+	// the replacement is limited like the runner, and frees the slot once closed.
+	_, err = replacement.RunCodeJSON(context.Background(), `
+		globalThis.more = new Uint8Array(256 << 20).fill(1);
+		while (true) {}`)
+	suite.ErrorIs(err, ErrRSSLimitExceeded)
+	suite.Eventually(func() bool { return replacement.Replacement() != nil }, time.Second, 10*time.Millisecond)
+	replacement.Replacement().Close()
+	suite.Eventually(func() bool { return pool.Running() == 0 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,94 @@
+package procrunner
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetireReason is why a runner closed itself.
+type RetireReason string
+
+const (
+	// RetireIdleTimeout is set by RecycleOption.IdleTimeout.
+	RetireIdleTimeout RetireReason = "idle_timeout"
+	// RetireMaxAge is set by RecycleOption.MaxAge.
+	RetireMaxAge RetireReason = "max_age"
+	// RetireMaxExecutions is set by RecycleOption.MaxExecutions.
+	RetireMaxExecutions RetireReason = "max_executions"
+	// RetireUnhealthy is set by HealthCheckOption.
+	RetireUnhealthy RetireReason = "unhealthy"
+	// RetireRSSLimit is set by MaxRSSOption.
+	RetireRSSLimit RetireReason = "rss_limit"
+)
+
+// retireMetrics are the counters of the reasons of RecycleOption.
+var retireMetrics = map[RetireReason]string{
+	RetireIdleTimeout:   MetricRetiredIdle,
+	RetireMaxAge:        MetricRetiredMaxAge,
+	RetireMaxExecutions: MetricRetiredMaxExecutions,
+}
+
+// defaultRecycleInterval is the interval of a RecycleOption without Interval.
+const defaultRecycleInterval = time.Second
+
+// RecycleOption retires the runner once it is idle and one of its limits is reached, so
+// that a long-lived process does not accumulate fragmentation and leaked globals: the
+// runner is closed, and a ProcRunnerPool created with the option frees its slot for a new
+// runner. The limits are checked every Interval, every second by default, and never while
+// a request is pending. A ProcRunnerPool checks them from its janitor, in the same pass as
+// the health checks. 0 disables a limit. See ProcRunner.RetireReason.
+type RecycleOption struct {
+	// IdleTimeout retires a runner without requests for this long, pings excluded.
+	IdleTimeout time.Duration
+	// MaxAge retires a runner whose process has run for this long.
+	MaxAge time.Duration
+	// MaxExecutions retires a runner once it has been sent this many requests, pings excluded.
+	MaxExecutions int
+	Interval      time.Duration
+}
+
+func (o RecycleOption) apply(r *ProcRunner) {
+	r.recycle = &o
+}
+
+// RetireReason returns why the runner closed itself, "" if it has not.
+func (r *ProcRunner) RetireReason() RetireReason {
+	if reason := r.retired.Load(); reason != nil {
+		return *reason
+	}
+	return ""
+}
+
+// retire records why the runner closes itself, unless it has closed for another reason.
+func (r *ProcRunner) retire(reason RetireReason) {
+	if r.retired.CompareAndSwap(nil, &reason) {
+		log.Debug().Str("reason", string(reason)).Msg("v8 runner retired")
+	}
+}
+
+// retireIdle kills the process if the runner is idle and one of its limits is reached at
+// now, and returns the reason. It holds mu, so that no request is sent meanwhile. The reason
+// and its metric are recorded before the runner is closed, so that a closed runner tells why.
+func (r *ProcRunner) retireIdle(now time.Time) RetireReason {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.idleLocked() {
+		return ""
+	}
+	var reason RetireReason
+	switch o := r.recycle; {
+	case o.MaxExecutions > 0 && r.executions >= o.MaxExecutions:
+		reason = RetireMaxExecutions
+	case o.MaxAge > 0 && now.Sub(r.createdAt) >= o.MaxAge:
+		reason = RetireMaxAge
+	case o.IdleTimeout > 0 && now.Sub(r.lastActive) >= o.IdleTimeout:
+		reason = RetireIdleTimeout
+	default:
+		return ""
+	}
+	r.retire(reason)
+	r.metrics.IncCounter(retireMetrics[reason])
+	r.closeFn()
+	return reason
+}
@@ -0,0 +1,85 @@
+package procrunner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RecycleTestSuite struct {
+	suite.Suite
+}
+
+func TestRecycleTestSuite(t *testing.T) {
+	suite.Run(t, new(RecycleTestSuite))
+}
+
+func (suite *RecycleTestSuite) TestMaxExecutions() {
+	m := newRecorder()
+	pool := NewProcRunnerPool(1, RecycleOption{MaxExecutions: 2, Interval: 10 * time.Millisecond}, MetricsOption{Metrics: m})
+	runner, err := pool.NewRunner("expression.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	// the runner is tended by the janitor of the pool.
+	suite.True(pool.tendingNow())
+	for i := 0; i < 2; i++ {
+		_, err = runner.RunCodeJSON(context.Background(), "1")
+		suite.NoError(err)
+	}
+	suite.Eventually(runner.IsClosed, time.Second, 10*time.Millisecond)
+	suite.Equal(RetireMaxExecutions, runner.RetireReason())
+	suite.Equal(1, m.counter(MetricRetiredMaxExecutions))
+	_, err = runner.RunCodeJSON(context.Background(), "1")
+	suite.ErrorIs(err, ErrorClosed)
+
+	// the slot is freed for a new runner, and the janitor stops.
+	suite.Eventually(func() bool { return pool.Running() == 0 }, time.Second, 10*time.Millisecond)
+	suite.Eventually(func() bool { return !pool.tendingNow() }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *RecycleTestSuite) TestMaxAgeWaitsForRequests() {
+	runner, err := NewProcRunner("expression.js", 16, RecycleOption{MaxAge: 20 * time.Millisecond, Interval: 10 * time.Millisecond})
+	suite.Require().NoError(err)
+	defer runner.Close()
+	res, err := runner.RunCodeJSON(context.Background(), "const end = Date.now() + 200; while (Date.now() < end) {}; 1")
+	suite.NoError(err)
+	suite.Equal("1", res)
+	suite.Eventually(runner.IsClosed, time.Second, 10*time.Millisecond)
+	suite.Equal(RetireMaxAge, runner.RetireReason())
+}
+
+func (suite *RecycleTestSuite) TestIdleTimeout() {
+	runner, err := NewProcRunner("expression.js", 16,
+		RecycleOption{IdleTimeout: 100 * time.Millisecond, Interval: 10 * time.Millisecond},
+		HealthCheckOption{Interval: 10 * time.Millisecond})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	// requests keep the runner active.
+	for i := 0; i < 6; i++ {
+		_, err = runner.RunCodeJSON(context.Background(), "1")
+		suite.Require().NoError(err)
+		time.Sleep(30 * time.Millisecond)
+	}
+	suite.False(runner.IsClosed())
+	// pings do not.
+	suite.Eventually(runner.IsClosed, time.Second, 10*time.Millisecond)
+	suite.Equal(RetireIdleTimeout, runner.RetireReason())
+}
+
+func (suite *RecycleTestSuite) TestRetireReasons() {
+	runner, err := NewProcRunner("expression.js", 16, RecycleOption{MaxAge: time.Hour})
+	suite.Require().NoError(err)
+	runner.Close()
+	// closed by the caller.
+	suite.Equal(RetireReason(""), runner.RetireReason())
+
+	runner, err = NewProcRunner("expression.js", 16, HealthCheckOption{Interval: 10 * time.Millisecond})
+	suite.Require().NoError(err)
+	defer runner.Close()
+	suite.Require().NoError(runner.cmd.Process.Kill())
+	suite.Eventually(runner.IsClosed, time.Second, 10*time.Millisecond)
+	suite.Equal(RetireUnhealthy, runner.RetireReason())
+}
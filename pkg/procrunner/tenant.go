@@ -0,0 +1,258 @@
+package procrunner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var ErrTenantBudgetExceeded = fmt.Errorf("tenant memory budget exceeded")
+
+// TenantConfig limits the runners of a tenant of a ProcRunnerPool, see Acquire.
+type TenantConfig struct {
+	// Weight is the share of the slots the tenant is granted when tenants wait for
+	// slots, relative to the weights of the other tenants waiting. 0 means 1.
+	Weight float64
+	// MaxConcurrent bounds the runners of the tenant, 0 means the size of the pool.
+	MaxConcurrent int
+	// MemoryBudgetMB bounds the sum of the max heap sizes of the runners of the tenant,
+	// 0 means no limit.
+	MemoryBudgetMB uint
+}
+
+// TenantStats are the runners of a tenant, and the slots it has been granted.
+type TenantStats struct {
+	// Running is the number of runners of the tenant, MemoryMB the sum of their max heap sizes.
+	Running  int
+	MemoryMB uint
+	// Waiting is the number of calls of Acquire waiting for a slot.
+	Waiting int
+	// Acquired is the number of slots granted, WaitTime the total time they were waited for.
+	Acquired int
+	WaitTime time.Duration
+}
+
+// tenant is the state of a tenant, guarded by the mu of the pool.
+type tenant struct {
+	name       string
+	config     TenantConfig
+	configured bool
+
+	running  int
+	memoryMB uint
+	acquired int
+	waitTime time.Duration
+
+	// queue holds the waiters in order of arrival. pass orders the tenants with waiters:
+	// the one with the lowest pass is granted the next slot, and its pass grows by the
+	// inverse of its weight, so that the slots are granted in proportion to the weights.
+	queue []*waiter
+	pass  float64
+}
+
+// waiter is a call of Acquire waiting for a slot. ready is closed once it is granted
+// a slot, or failed with err.
+type waiter struct {
+	heapMB   uint
+	seq      uint64
+	enqueued time.Time
+	ready    chan struct{}
+	done     bool
+	err      error
+}
+
+func (t *tenant) fits(heapMB uint) bool {
+	if t.config.MaxConcurrent > 0 && t.running >= t.config.MaxConcurrent {
+		return false
+	}
+	return t.config.MemoryBudgetMB == 0 || t.memoryMB+heapMB <= t.config.MemoryBudgetMB
+}
+
+func (t *tenant) stride() float64 {
+	if t.config.Weight <= 0 {
+		return 1
+	}
+	return 1 / t.config.Weight
+}
+
+// SetTenant sets the limits of the tenant name.
+func (p *ProcRunnerPool) SetTenant(name string, config TenantConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := p.tenantLocked(name)
+	t.config, t.configured = config, true
+	p.dispatchLocked()
+}
+
+// SetDefaultTenant sets the limits of the tenants without SetTenant.
+func (p *ProcRunnerPool) SetDefaultTenant(config TenantConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.defaultTenant = config
+	for _, t := range p.tenants {
+		if !t.configured {
+			t.config = config
+		}
+	}
+	p.dispatchLocked()
+}
+
+// TenantStats returns the stats of the tenants set by SetTenant, and of the other tenants
+// while they have runners or calls of Acquire waiting. The stats of the latter are dropped
+// with them, so that the pool does not keep every tenant it has seen.
+func (p *ProcRunnerPool) TenantStats() map[string]TenantStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]TenantStats, len(p.tenants))
+	for name, t := range p.tenants {
+		stats[name] = TenantStats{
+			Running:  t.running,
+			MemoryMB: t.memoryMB,
+			Waiting:  len(t.queue),
+			Acquired: t.acquired,
+			WaitTime: t.waitTime,
+		}
+	}
+	return stats
+}
+
+// Acquire creates a runner for tenant, like NewRunner, once the pool has a slot and the
+// runners of tenant are within the limits of its TenantConfig. Unlike NewRunner, it waits
+// until then, or until ctx is done. Tenants waiting for slots are granted them in proportion
+// to their weights, and the calls of a tenant in order. It fails with ErrTenantBudgetExceeded
+// if maxheapsizemb exceeds the memory budget of tenant, since it would never fit.
+func (p *ProcRunnerPool) Acquire(
+	ctx context.Context,
+	tenant string,
+	filename string,
+	maxheapsizemb uint,
+	options ...Option,
+) (*ProcRunner, error) {
+	p.mu.Lock()
+	if p.shutdown {
+		p.mu.Unlock()
+		return nil, ErrPoolShutdown
+	}
+	t := p.tenantLocked(tenant)
+	if budget := t.config.MemoryBudgetMB; budget > 0 && maxheapsizemb > budget {
+		p.dropLocked(t)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d MB exceeds %d MB", ErrTenantBudgetExceeded, maxheapsizemb, budget)
+	}
+	p.seq++
+	w := &waiter{heapMB: maxheapsizemb, seq: p.seq, enqueued: time.Now(), ready: make(chan struct{})}
+	if len(t.queue) == 0 {
+		// a tenant does not accumulate passes while it does not wait.
+		t.pass = max(t.pass, p.pass)
+	}
+	t.queue = append(t.queue, w)
+	p.metrics.AddGauge(MetricPoolWaiting, 1)
+	p.dispatchLocked()
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		p.mu.Lock()
+		switch {
+		case !w.done:
+			p.removeWaiterLocked(t, w)
+		case w.err == nil:
+			// granted meanwhile.
+			p.releaseLocked(t, maxheapsizemb)
+		}
+		p.mu.Unlock()
+		if w.err != nil {
+			return nil, w.err
+		}
+		return nil, ctx.Err()
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return p.start(t, filename, maxheapsizemb, options)
+}
+
+func (p *ProcRunnerPool) tenantLocked(name string) *tenant {
+	t, ok := p.tenants[name]
+	if !ok {
+		t = &tenant{name: name, config: p.defaultTenant, pass: p.pass}
+		p.tenants[name] = t
+	}
+	return t
+}
+
+// dropLocked drops t once it has no runners and no waiters, unless it was set by SetTenant.
+// It is created again by its next call of Acquire, with the pass of the pool, as a tenant
+// that waits again does not keep the pass it had.
+func (p *ProcRunnerPool) dropLocked(t *tenant) {
+	if !t.configured && t.running == 0 && len(t.queue) == 0 {
+		delete(p.tenants, t.name)
+	}
+}
+
+// dispatchLocked grants the free slots to the waiters: to the first waiter of the tenant
+// with the lowest pass among the tenants within their limits, the earliest waiter first
+// on a tie.
+func (p *ProcRunnerPool) dispatchLocked() {
+	for p.running < p.max {
+		var next *tenant
+		for _, t := range p.tenants {
+			if len(t.queue) == 0 || !t.fits(t.queue[0].heapMB) {
+				continue
+			}
+			if next == nil || t.pass < next.pass || (t.pass == next.pass && t.queue[0].seq < next.queue[0].seq) {
+				next = t
+			}
+		}
+		if next == nil {
+			return
+		}
+		w := next.queue[0]
+		next.queue = next.queue[1:]
+		p.metrics.AddGauge(MetricPoolWaiting, -1)
+		p.pass = next.pass
+		next.pass += next.stride()
+		next.waitTime += time.Since(w.enqueued)
+		p.reserveLocked(next, w.heapMB)
+		w.done = true
+		close(w.ready)
+	}
+}
+
+// waitingLocked reports whether a call of Acquire is waiting for a slot.
+func (p *ProcRunnerPool) waitingLocked() bool {
+	for _, t := range p.tenants {
+		if len(t.queue) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removeWaiterLocked removes w, which gave up, from the queue of t.
+func (p *ProcRunnerPool) removeWaiterLocked(t *tenant, w *waiter) {
+	for i, queued := range t.queue {
+		if queued == w {
+			t.queue = append(t.queue[:i], t.queue[i+1:]...)
+			break
+		}
+	}
+	p.metrics.AddGauge(MetricPoolWaiting, -1)
+	p.dropLocked(t)
+	// the next waiter of t may fit where w did not.
+	p.dispatchLocked()
+}
+
+// failWaitersLocked fails every waiter with err.
+func (p *ProcRunnerPool) failWaitersLocked(err error) {
+	for _, t := range p.tenants {
+		for _, w := range t.queue {
+			w.done, w.err = true, err
+			close(w.ready)
+			p.metrics.AddGauge(MetricPoolWaiting, -1)
+		}
+		t.queue = nil
+		p.dropLocked(t)
+	}
+}
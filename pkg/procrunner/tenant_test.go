@@ -0,0 +1,181 @@
+package procrunner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TenantTestSuite struct {
+	suite.Suite
+}
+
+func TestTenantTestSuite(t *testing.T) {
+	suite.Run(t, new(TenantTestSuite))
+}
+
+func (suite *TenantTestSuite) TestMaxConcurrent() {
+	pool := NewProcRunnerPool(3)
+	pool.SetDefaultTenant(TenantConfig{MaxConcurrent: 1})
+	runner, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.Require().NoError(err)
+
+	// the pool has free slots, but not for a.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx, "a", "test.js", 16)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	other, err := pool.Acquire(context.Background(), "b", "test.js", 16)
+	suite.Require().NoError(err)
+	defer other.Close()
+
+	acquired := make(chan *ProcRunner)
+	go func() {
+		r, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+		suite.NoError(err)
+		acquired <- r
+	}()
+	suite.Eventually(func() bool { return pool.TenantStats()["a"].Waiting == 1 }, time.Second, 10*time.Millisecond)
+	runner.Close()
+	next := <-acquired
+	defer next.Close()
+
+	stats := pool.TenantStats()["a"]
+	suite.Equal(1, stats.Running)
+	suite.Equal(uint(16), stats.MemoryMB)
+	suite.Equal(0, stats.Waiting)
+	suite.Equal(2, stats.Acquired)
+	suite.Greater(stats.WaitTime, time.Duration(0))
+}
+
+func (suite *TenantTestSuite) TestNewRunnerDoesNotSkipTheQueue() {
+	pool := NewProcRunnerPool(2)
+	pool.SetDefaultTenant(TenantConfig{MaxConcurrent: 1})
+	runner, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.Require().NoError(err)
+
+	acquired := make(chan *ProcRunner)
+	go func() {
+		r, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+		suite.NoError(err)
+		acquired <- r
+	}()
+	suite.Eventually(func() bool { return pool.TenantStats()["a"].Waiting == 1 }, time.Second, 10*time.Millisecond)
+	// a slot is free, but it is kept for the waiting tenant.
+	_, err = pool.NewRunner("test.js", 16)
+	suite.ErrorIs(err, ErrMaxReached)
+	runner.Close()
+	next := <-acquired
+	defer next.Close()
+
+	// no tenant waits anymore.
+	other, err := pool.NewRunner("test.js", 16)
+	suite.Require().NoError(err)
+	other.Close()
+}
+
+func (suite *TenantTestSuite) TestMemoryBudget() {
+	pool := NewProcRunnerPool(3)
+	pool.SetTenant("a", TenantConfig{MemoryBudgetMB: 48})
+	_, err := pool.Acquire(context.Background(), "a", "test.js", 64)
+	suite.ErrorIs(err, ErrTenantBudgetExceeded)
+
+	runner, err := pool.Acquire(context.Background(), "a", "test.js", 32)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx, "a", "test.js", 32)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	small, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.Require().NoError(err)
+	defer small.Close()
+	stats := pool.TenantStats()["a"]
+	suite.Equal(2, stats.Running)
+	suite.Equal(uint(48), stats.MemoryMB)
+	suite.Equal(2, stats.Acquired)
+}
+
+func (suite *TenantTestSuite) TestWeightedFairQueuing() {
+	pool := NewProcRunnerPool(1)
+	pool.SetTenant("a", TenantConfig{Weight: 2})
+	pool.SetTenant("b", TenantConfig{Weight: 1})
+	busy, err := pool.NewRunner("test.js", 16)
+	suite.Require().NoError(err)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	acquire := func(tenant string) {
+		defer wg.Done()
+		r, err := pool.Acquire(context.Background(), tenant, "test.js", 16)
+		if !suite.NoError(err) {
+			return
+		}
+		mu.Lock()
+		order = append(order, tenant)
+		mu.Unlock()
+		r.Close()
+	}
+	// a single slot, granted to one waiter at a time.
+	for i, tenant := range []string{"a", "a", "a", "a", "a", "a", "b", "b", "b", "b", "b", "b"} {
+		wg.Add(1)
+		go acquire(tenant)
+		suite.Eventually(func() bool {
+			stats := pool.TenantStats()
+			return stats["a"].Waiting+stats["b"].Waiting == i+1
+		}, time.Second, time.Millisecond)
+	}
+	busy.Close()
+	wg.Wait()
+	suite.Equal([]string{"a", "b", "a", "a", "b", "a", "a", "b", "a", "b", "b", "b"}, order)
+	suite.Equal(6, pool.TenantStats()["a"].Acquired)
+	suite.Equal(6, pool.TenantStats()["b"].Acquired)
+}
+
+func (suite *TenantTestSuite) TestShutdown() {
+	pool := NewProcRunnerPool(1)
+	runner, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	failed := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background(), "b", "test.js", 16)
+		failed <- err
+	}()
+	suite.Eventually(func() bool { return pool.TenantStats()["b"].Waiting == 1 }, time.Second, 10*time.Millisecond)
+	_, err = pool.Shutdown(context.Background())
+	suite.NoError(err)
+	suite.ErrorIs(<-failed, ErrPoolShutdown)
+	_, err = pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.ErrorIs(err, ErrPoolShutdown)
+}
+
+func (suite *TenantTestSuite) TestDropIdleTenants() {
+	pool := NewProcRunnerPool(1)
+	pool.SetTenant("configured", TenantConfig{Weight: 2})
+	runner, err := pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.Require().NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx, "b", "test.js", 16)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	// b gave up, and has no runner.
+	suite.NotContains(pool.TenantStats(), "b")
+	suite.Contains(pool.TenantStats(), "a")
+
+	runner.Close()
+	suite.Eventually(func() bool { return pool.Running() == 0 }, time.Second, 10*time.Millisecond)
+	stats := pool.TenantStats()
+	suite.NotContains(stats, "a")
+	// the tenants set by SetTenant are kept.
+	suite.Contains(stats, "configured")
+
+	runner, err = pool.Acquire(context.Background(), "a", "test.js", 16)
+	suite.Require().NoError(err)
+	defer runner.Close()
+	suite.Equal(1, pool.TenantStats()["a"].Acquired)
+}
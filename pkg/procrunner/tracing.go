@@ -0,0 +1,265 @@
+package procrunner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stumble/v8runner/pkg/types"
+)
+
+// Tracer opens the spans of the requests of a runner, see TracerOption. It is meant to be
+// implemented by an adapter of a tracing library, e.g. OpenTelemetry.
+type Tracer interface {
+	// Start opens the span name started at start, as a child of the span of ctx if any,
+	// and returns a context holding the new span.
+	Start(ctx context.Context, name string, start time.Time) (context.Context, Span)
+}
+
+// Span is a span opened by a Tracer. Its methods are not called concurrently.
+type Span interface {
+	// TraceID is the ID of the trace of the span, "" if it is not traced.
+	TraceID() string
+	// SetAttributes sets attrs on the span.
+	SetAttributes(attrs ...Attribute)
+	// RecordError marks the span as failed by err.
+	RecordError(err error)
+	// End closes the span at end.
+	End(end time.Time)
+}
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// The spans of a request. SpanRequest lasts from sending the request to its result, and
+// is the parent of the others.
+const (
+	SpanRequest = "v8runner.request"
+	// SpanEncode is the time writing the request to the process.
+	SpanEncode = "v8runner.encode"
+	// SpanExecute is the time the process executes the request, from the response to the
+	// request sent before it, to the first byte of its own response.
+	SpanExecute = "v8runner.execute"
+	// SpanDecode is the time reading the last response of the request.
+	SpanDecode = "v8runner.decode"
+)
+
+// The attributes of SpanRequest.
+const (
+	AttrRequestID    = "v8runner.request_id"
+	AttrRequestType  = "v8runner.request_type"
+	AttrResponseType = "v8runner.response_type"
+	// AttrCodeHash is the hex SHA-256 of the code of the request.
+	AttrCodeHash = "v8runner.code_hash"
+	// AttrBytesIn is the size of the encoded request.
+	AttrBytesIn = "v8runner.bytes_in"
+	// AttrBytesOut is the size of the JSON results of the responses.
+	AttrBytesOut = "v8runner.bytes_out"
+	// AttrOutcome is one of the Outcome constants.
+	AttrOutcome = "v8runner.outcome"
+)
+
+// The outcomes of a request.
+const (
+	OutcomeOK      = "ok"
+	OutcomeError   = "error"
+	OutcomeTimeout = "timeout"
+	OutcomeKilled  = "killed"
+	OutcomeClosed  = "closed"
+)
+
+// TracerOption opens the spans of each request with Tracer, as children of the span of
+// the context of the request. The trace ID is sent to the process, which marks the start
+// of each request on stderr, so that the lines it logs are tagged with the trace ID.
+type TracerOption struct {
+	Tracer Tracer
+}
+
+func (o TracerOption) apply(r *ProcRunner) {
+	r.tracer = o.Tracer
+}
+
+// trace holds the spans of a call. It ends once the request is both written and resolved,
+// whichever comes last, since the response may be read before the write returns.
+type trace struct {
+	tracer Tracer
+	ctx    context.Context
+	span   Span
+	refs   atomic.Int32
+
+	mu          sync.Mutex
+	attrs       []Attribute
+	encodeStart time.Time
+	encodeEnd   time.Time
+	started     time.Time
+	received    time.Time
+	decoded     time.Time
+	resolved    time.Time
+	bytesOut    int
+	err         error
+}
+
+func startTrace(ctx context.Context, tracer Tracer, req types.RunCodeRequest) *trace {
+	t := &trace{tracer: tracer}
+	t.ctx, t.span = tracer.Start(ctx, SpanRequest, time.Now())
+	t.refs.Store(2)
+	hash := sha256.Sum256([]byte(req.Code))
+	reqType := req.Type
+	if reqType == "" {
+		reqType = types.ReqTypeRun
+	}
+	t.attrs = []Attribute{
+		{Key: AttrRequestType, Value: string(reqType)},
+		{Key: AttrResponseType, Value: string(req.ResponseType)},
+		{Key: AttrCodeHash, Value: hex.EncodeToString(hash[:])},
+	}
+	return t
+}
+
+// wrote records the write of the request with ID id, of n bytes.
+func (t *trace) wrote(id string, start, end time.Time, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attrs = append(t.attrs, Attribute{Key: AttrRequestID, Value: id}, Attribute{Key: AttrBytesIn, Value: n})
+	t.encodeStart, t.encodeEnd = start, end
+}
+
+// start records that the process starts executing the request.
+func (t *trace) start(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started = at
+}
+
+// receive records a response, whose first byte was read at received.
+func (t *trace) receive(res types.RunCodeResponse, received, decoded time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.received.IsZero() || !res.More {
+		t.received, t.decoded = received, decoded
+	}
+	if res.Result != nil {
+		t.bytesOut += len(*res.Result)
+	}
+	for _, item := range res.Items {
+		if item.Result != nil {
+			t.bytesOut += len(*item.Result)
+		}
+	}
+}
+
+func (t *trace) resolve(res types.RunCodeResponse, err error) {
+	if err == nil && res.Error != nil {
+		err = responseError(*res.Error, res.ErrorCode)
+	}
+	t.mu.Lock()
+	t.resolved = time.Now()
+	t.err = err
+	t.mu.Unlock()
+	t.done()
+}
+
+// done releases a reference, and ends the spans once the last one is released.
+func (t *trace) done() {
+	if t.refs.Add(-1) == 0 {
+		t.end()
+	}
+}
+
+func (t *trace) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.encodeStart.IsZero() {
+		_, span := t.tracer.Start(t.ctx, SpanEncode, t.encodeStart)
+		span.End(t.encodeEnd)
+
+		start := t.encodeEnd
+		if t.started.After(start) {
+			start = t.started
+		}
+		end := t.received
+		if end.IsZero() {
+			end = t.resolved
+		}
+		_, span = t.tracer.Start(t.ctx, SpanExecute, start)
+		if t.received.IsZero() && t.err != nil {
+			span.RecordError(t.err)
+		}
+		span.End(end)
+	}
+	if !t.received.IsZero() {
+		_, span := t.tracer.Start(t.ctx, SpanDecode, t.received)
+		span.End(t.decoded)
+	}
+
+	t.span.SetAttributes(append(t.attrs,
+		Attribute{Key: AttrBytesOut, Value: t.bytesOut},
+		Attribute{Key: AttrOutcome, Value: outcome(t.err)})...)
+	if t.err != nil {
+		t.span.RecordError(t.err)
+	}
+	t.span.End(t.resolved)
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, ErrorTimeout):
+		return OutcomeTimeout
+	case errors.Is(err, ErrorKilled):
+		return OutcomeKilled
+	case errors.Is(err, ErrorClosed):
+		return OutcomeClosed
+	default:
+		return OutcomeError
+	}
+}
+
+// countingWriter counts the bytes written to the process, guarded by the writeMu of the runner.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// readTimer records when the first bytes of a response are read, used by the reader only.
+type readTimer struct {
+	r     io.Reader
+	first time.Time
+}
+
+func (t *readTimer) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 && t.first.IsZero() {
+		t.first = time.Now()
+	}
+	return n, err
+}
+
+// reset forgets the first byte of the previous response.
+func (t *readTimer) reset() {
+	t.first = time.Time{}
+}
+
+// firstRead is when the first byte of the response was read, or start if it was read
+// along with the previous response.
+func (t *readTimer) firstRead(start time.Time) time.Time {
+	if t.first.IsZero() {
+		return start
+	}
+	return t.first
+}
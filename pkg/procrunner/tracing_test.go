@@ -0,0 +1,166 @@
+package procrunner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordedSpan is a span recorded by recordingTracer.
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	start, end time.Time
+	attrs      map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) TraceID() string { return "4bf92f3577b34da6a3ce929d0e0e4736" }
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+
+func (s *recordedSpan) End(end time.Time) {
+	s.end = end
+	s.ended = true
+}
+
+type spanKey struct{}
+
+// recordingTracer records the spans it opens.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, start time.Time) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, start: start, attrs: make(map[string]any)}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// children returns the spans of parent by name.
+func (t *recordingTracer) children(parent *recordedSpan) map[string]*recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make(map[string]*recordedSpan)
+	for _, span := range t.spans {
+		if span.parent == parent {
+			spans[span.name] = span
+		}
+	}
+	return spans
+}
+
+type TracingTestSuite struct {
+	suite.Suite
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+func (suite *TracingTestSuite) TestSpans() {
+	tracer := &recordingTracer{}
+	runner, err := NewProcRunner("expression.js", 16, TracerOption{Tracer: tracer})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	ctx, root := tracer.Start(context.Background(), "caller", time.Now())
+	res, err := runner.RunCodeJSON(ctx, `"abc".repeat(2)`)
+	suite.Require().NoError(err)
+	suite.Equal(`"abcabc"`, res)
+
+	requests := tracer.children(root.(*recordedSpan))
+	suite.Require().Len(requests, 1)
+	request := requests[SpanRequest]
+	suite.Require().NotNil(request)
+	suite.True(request.ended)
+	suite.NoError(request.err)
+	suite.Equal("1", request.attrs[AttrRequestID])
+	suite.Equal("run", request.attrs[AttrRequestType])
+	suite.Equal("json", request.attrs[AttrResponseType])
+	hash := sha256.Sum256([]byte(`"abc".repeat(2)`))
+	suite.Equal(hex.EncodeToString(hash[:]), request.attrs[AttrCodeHash])
+	suite.Greater(request.attrs[AttrBytesIn], 0)
+	suite.Equal(len(`"abcabc"`), request.attrs[AttrBytesOut])
+	suite.Equal(OutcomeOK, request.attrs[AttrOutcome])
+
+	spans := tracer.children(request)
+	suite.Require().Len(spans, 3)
+	encode, execute, decode := spans[SpanEncode], spans[SpanExecute], spans[SpanDecode]
+	for _, span := range []*recordedSpan{encode, execute, decode} {
+		suite.Require().NotNil(span)
+		suite.True(span.ended)
+		suite.False(span.end.Before(span.start), span.name)
+		suite.False(span.start.Before(request.start), span.name)
+		suite.False(span.end.After(request.end), span.name)
+	}
+	suite.False(execute.start.Before(encode.end))
+	suite.False(decode.start.Before(execute.end))
+}
+
+func (suite *TracingTestSuite) TestOutcome() {
+	tracer := &recordingTracer{}
+	runner, err := NewProcRunner("expression.js", 16, TracerOption{Tracer: tracer})
+	suite.Require().NoError(err)
+	defer runner.Close()
+
+	_, err = runner.RunCodeJSON(context.Background(), `throw new Error("failed")`)
+	suite.Error(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = runner.RunCodeJSON(ctx, "while(true){}")
+	suite.Equal(ErrorTimeout, err)
+	runner.Close()
+	_, err = runner.RunCodeJSON(context.Background(), "1")
+	suite.ErrorIs(err, ErrorClosed)
+
+	var outcomes []any
+	for _, span := range tracer.spans {
+		if span.name == SpanRequest {
+			suite.True(span.ended)
+			suite.Error(span.err)
+			outcomes = append(outcomes, span.attrs[AttrOutcome])
+		}
+	}
+	suite.Equal([]any{OutcomeError, OutcomeTimeout, OutcomeClosed}, outcomes)
+}
+
+func (suite *TracingTestSuite) TestStderrTraceID() {
+	var logs bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	defer func() { log.Logger = logger }()
+
+	r := &ProcRunner{stderr: io.NopCloser(strings.NewReader(
+		"v8runner version: 0.0.4\n" +
+			"v8runner trace_id=4bf92f3577b34da6a3ce929d0e0e4736\n" +
+			"Fatal JavaScript out of memory\n" +
+			"v8runner trace_id=\n" +
+			"after\n"))}
+	r.logStderr()
+	suite.Equal(`{"level":"debug","message":"v8 stderr: v8runner version: 0.0.4"}
+{"level":"debug","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","message":"v8 stderr: Fatal JavaScript out of memory"}
+{"level":"debug","message":"v8 stderr: after"}
+`, logs.String())
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var ErrCPUBudgetExceeded = fmt.Errorf("cpu budget exceeded")
+
+type cpuBudgetKey struct{}
+
+// cpuBudget is the CPU time left to the executions of one request.
+// The executions of a runner are sequential, so it is not synchronized.
+type cpuBudget struct {
+	limit     time.Duration
+	remaining time.Duration
+}
+
+// WithCPUBudget returns a context that bounds the CPU time of every execution run with it,
+// e.g. all the calls of a map request, to budget in total. The CPU time is measured on the
+// thread running the script (wall time outside of linux), so unlike a context deadline it is
+// not affected by the scheduling of other processes. An execution exceeding the budget is
+// terminated and fails with ErrCPUBudgetExceeded, and the runner stays usable.
+func WithCPUBudget(ctx context.Context, budget time.Duration) context.Context {
+	return context.WithValue(ctx, cpuBudgetKey{}, &cpuBudget{limit: budget, remaining: budget})
+}
+
+// cpuBudgetFrom returns the budget of ctx, nil if there is none.
+func cpuBudgetFrom(ctx context.Context) *cpuBudget {
+	b, _ := ctx.Value(cpuBudgetKey{}).(*cpuBudget)
+	return b
+}
+
+func (b *cpuBudget) exhausted() bool {
+	return b != nil && b.remaining <= 0
+}
+
+func (b *cpuBudget) consume(cpu time.Duration) {
+	if b == nil {
+		return
+	}
+	b.remaining -= cpu
+}
+
+func (b *cpuBudget) err() error {
+	return fmt.Errorf("%w: %s", ErrCPUBudgetExceeded, b.limit)
+}
+
+// pollInterval is how often the CPU time of an execution is checked.
+func (b *cpuBudget) pollInterval() time.Duration {
+	const minInterval, maxInterval = time.Millisecond, 10 * time.Millisecond
+	return min(max(b.remaining/10, minInterval), maxInterval)
+}
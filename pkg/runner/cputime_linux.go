@@ -0,0 +1,32 @@
+//go:build linux
+
+package runner
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// threadClock is the CPU clock of an OS thread.
+type threadClock struct {
+	clockID int32
+}
+
+// currentThreadClock returns the CPU clock of the calling thread, which must be
+// locked to the goroutine.
+func currentThreadClock() threadClock {
+	// the clock id of a thread, as computed by MAKE_THREAD_CPUCLOCK(tid, CPUCLOCK_SCHED)
+	// in the kernel, so that it can be read from other threads.
+	tid := unix.Gettid()
+	return threadClock{clockID: int32(^tid<<3 | 6)}
+}
+
+// Now returns the CPU time consumed by the thread.
+func (c threadClock) Now() time.Duration {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(c.clockID, &ts); err != nil {
+		return 0
+	}
+	return time.Duration(ts.Nano())
+}
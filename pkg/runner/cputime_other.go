@@ -0,0 +1,22 @@
+//go:build !linux
+
+package runner
+
+import (
+	"time"
+)
+
+// threadClock falls back to the wall clock where the CPU clock of another
+// thread cannot be read.
+type threadClock struct{}
+
+func currentThreadClock() threadClock {
+	return threadClock{}
+}
+
+// Now returns the wall time since an arbitrary point.
+func (c threadClock) Now() time.Duration {
+	return time.Since(processStart)
+}
+
+var processStart = time.Now()
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// deterministic is the script replacing the non-deterministic APIs of DeterministicOption.
+//
+//go:embed deterministic.js
+var deterministic string
+
+// DeterministicOption replaces the non-deterministic APIs of the context, so that a
+// script evaluated again with the same types.Determinism yields the same result:
+// Math.random is a seeded generator, and Date.now, new Date(), Date(), performance.now
+// and Intl.DateTimeFormat read a virtual clock. See Runner.Seed.
+// NOTE: the local time of dates depends on the TZ environment variable of the process,
+// which must be the same too, e.g. UTC.
+type DeterministicOption struct{}
+
+func (o DeterministicOption) apply(c *config) error {
+	c.deterministic = true
+	return nil
+}
+
+// defineDeterminism replaces the non-deterministic APIs of the context, if enabled,
+// and seeds them with the zero Determinism.
+func (r *Runner) defineDeterminism() error {
+	if !r.config.deterministic {
+		return nil
+	}
+	val, err := r.codeCtx.RunScript(deterministic, "deterministic.js")
+	if err != nil {
+		return fmt.Errorf("failed to define determinism because: %w", err)
+	}
+	install, err := val.AsFunction()
+	if err != nil {
+		return fmt.Errorf("failed to define determinism because: %w", err)
+	}
+	val, err = install.Call(v8.Undefined(r.vm))
+	if err != nil {
+		return fmt.Errorf("failed to define determinism because: %w", err)
+	}
+	if r.seed, err = val.AsFunction(); err != nil {
+		return fmt.Errorf("failed to define determinism because: %w", err)
+	}
+	return r.Seed(types.Determinism{})
+}
+
+// Seed resets the random numbers and the clock of a runner created with DeterministicOption
+// to d, so that the executions that follow yield the same results as after any other Seed
+// with the same d. It fails if the runner is not deterministic.
+func (r *Runner) Seed(d types.Determinism) error {
+	if r.closed {
+		return fmt.Errorf("runner is closed")
+	}
+	if !r.config.deterministic {
+		return fmt.Errorf("runner is not deterministic")
+	}
+	var clock int64
+	if !d.Clock.IsZero() {
+		clock = d.Clock.UnixMilli()
+	}
+	args := make([]v8.Valuer, 0, 4)
+	for _, arg := range []int64{d.Seed >> 32, d.Seed & 0xffffffff, clock, d.ClockStep.Milliseconds()} {
+		// float64 is exact for the 32 bits halves of the seed, and for the milliseconds.
+		val, err := v8.NewValue(r.vm, float64(arg))
+		if err != nil {
+			return err
+		}
+		args = append(args, val)
+	}
+	if _, err := r.seed.Call(v8.Undefined(r.vm), args...); err != nil {
+		return fmt.Errorf("failed to seed because: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Replaces the non-deterministic APIs of the context, see DeterministicOption.
+// The script evaluates to a function installing the replacements, which returns the
+// function seeding them.
+(function () {
+  "use strict";
+
+  let state = [0, 0, 0, 0]; // the state of the sfc32 generator
+  let clock = 0; // the time of the virtual clock, in ms since the epoch
+  let origin = 0; // the time origin of performance.now
+  let step = 0; // how much the virtual clock advances each time it is read
+
+  const tick = () => {
+    const t = clock;
+    clock += step;
+    return t;
+  };
+
+  // next returns the next number of the sfc32 generator, in [0, 1).
+  const next = () => {
+    let [a, b, c, d] = state;
+    const t = (((a + b) | 0) + d) | 0;
+    d = (d + 1) | 0;
+    a = b ^ (b >>> 9);
+    b = (c + (c << 3)) | 0;
+    c = (c << 21) | (c >>> 11);
+    c = (c + t) | 0;
+    state = [a, b, c, d];
+    return (t >>> 0) / 4294967296;
+  };
+
+  Object.defineProperty(Math, "random", {
+    value: function random() {
+      return next();
+    },
+    writable: true,
+    configurable: true,
+  });
+
+  const RealDate = Date;
+  const VirtualDate = function Date(...args) {
+    if (new.target === undefined) {
+      return new RealDate(tick()).toString();
+    }
+    return Reflect.construct(RealDate, args.length === 0 ? [tick()] : args, new.target);
+  };
+  Object.defineProperty(VirtualDate, "length", { value: 7 });
+  Object.defineProperty(VirtualDate, "prototype", { value: RealDate.prototype });
+  Object.defineProperty(RealDate.prototype, "constructor", { value: VirtualDate, writable: true, configurable: true });
+  for (const name of ["parse", "UTC"]) {
+    Object.defineProperty(VirtualDate, name, { value: RealDate[name], writable: true, configurable: true });
+  }
+  Object.defineProperty(VirtualDate, "now", {
+    value: function now() {
+      return tick();
+    },
+    writable: true,
+    configurable: true,
+  });
+  Object.defineProperty(globalThis, "Date", { value: VirtualDate, writable: true, configurable: true });
+
+  // Intl.DateTimeFormat formats the current time when it is given no date.
+  if (typeof Intl === "object") {
+    const DateTimeFormat = Intl.DateTimeFormat.prototype;
+    const format = Object.getOwnPropertyDescriptor(DateTimeFormat, "format").get;
+    Object.defineProperty(DateTimeFormat, "format", {
+      get() {
+        const fn = format.call(this);
+        return (date) => fn(date === undefined ? tick() : date);
+      },
+      configurable: true,
+    });
+    const formatToParts = DateTimeFormat.formatToParts;
+    Object.defineProperty(DateTimeFormat, "formatToParts", {
+      value: function (date) {
+        return formatToParts.call(this, date === undefined ? tick() : date);
+      },
+      writable: true,
+      configurable: true,
+    });
+  }
+
+  const performance = globalThis.performance || {};
+  Object.defineProperty(performance, "now", {
+    value: function now() {
+      return tick() - origin;
+    },
+    writable: true,
+    configurable: true,
+  });
+  Object.defineProperty(performance, "timeOrigin", { get: () => origin, configurable: true });
+  if (!globalThis.performance) {
+    Object.defineProperty(globalThis, "performance", { value: performance, writable: true, configurable: true });
+  }
+
+  // seed resets the generator with the 64 bits seed hi:lo, and the virtual clock to time.
+  return function seed(hi, lo, time, clockStep) {
+    // seeds sfc32 like its reference implementation, then discards the first outputs.
+    state = [0, hi | 0, lo | 0, 1];
+    for (let i = 0; i < 12; i++) {
+      next();
+    }
+    clock = time;
+    origin = time;
+    step = clockStep;
+  };
+});
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/stumble/v8runner/pkg/types"
+)
+
+type DeterministicTestSuite struct {
+	suite.Suite
+	runner *Runner
+}
+
+func TestDeterministicTestSuite(t *testing.T) {
+	suite.Run(t, new(DeterministicTestSuite))
+}
+
+func (suite *DeterministicTestSuite) SetupTest() {
+	r, err := NewRunner("test.js", DeterministicOption{})
+	suite.Require().NoError(err)
+	suite.runner = r
+}
+
+func (suite *DeterministicTestSuite) TearDownTest() {
+	suite.runner.Close()
+}
+
+func (suite *DeterministicTestSuite) run(d types.Determinism, script string) string {
+	suite.Require().NoError(suite.runner.Seed(d))
+	val, err := suite.runner.RunScript(context.Background(), script)
+	suite.Require().NoError(err)
+	return val.String()
+}
+
+func (suite *DeterministicTestSuite) TestRandom() {
+	const script = `Array.from({length: 5}, () => Math.random()).join()`
+	first := suite.run(types.Determinism{Seed: 42}, script)
+	suite.Equal(first, suite.run(types.Determinism{Seed: 42}, script))
+	suite.NotEqual(first, suite.run(types.Determinism{Seed: 43}, script))
+	suite.NotEqual(first, suite.run(types.Determinism{Seed: 42 + 1<<32}, script))
+	suite.Equal("true", suite.run(types.Determinism{Seed: -1},
+		`Array.from({length: 1000}, () => Math.random()).every((x) => x >= 0 && x < 1)`))
+
+	// a new runner replays the same numbers.
+	r, err := NewRunner("test.js", DeterministicOption{})
+	suite.Require().NoError(err)
+	defer r.Close()
+	suite.Require().NoError(r.Seed(types.Determinism{Seed: 42}))
+	val, err := r.RunScript(context.Background(), script)
+	suite.Require().NoError(err)
+	suite.Equal(first, val.String())
+}
+
+func (suite *DeterministicTestSuite) TestClock() {
+	clock := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	fixed := types.Determinism{Clock: clock}
+	suite.Equal("1709209800000,1709209800000,2024-02-29T12:30:00.000Z,true",
+		suite.run(fixed, `[Date.now(), Date.now(), new Date().toISOString(), typeof Date() === "string"].join()`))
+	suite.Equal("0,0", suite.run(fixed, `[performance.now(), performance.now()].join()`))
+
+	stepped := types.Determinism{Clock: clock, ClockStep: 10 * time.Millisecond}
+	suite.Equal("1709209800000,1709209800010,20", suite.run(stepped, `[Date.now(), Date.now(), performance.now()].join()`))
+
+	// the zero clock is the Unix epoch.
+	suite.Equal("0", suite.run(types.Determinism{}, `Date.now()`))
+	suite.Equal("2024", suite.run(fixed, `new Intl.DateTimeFormat("en", {year: "numeric", timeZone: "UTC"}).format()`))
+}
+
+func (suite *DeterministicTestSuite) TestDate() {
+	suite.Equal("true,true,true,0,7,true", suite.run(types.Determinism{},
+		`[new Date() instanceof Date, new Date(5).getTime() === 5, Date.UTC(1970, 0) === 0,
+		  Date.parse("1970-01-01T00:00:00Z"), Date.length, new Date().constructor === Date].join()`))
+	suite.Equal("true", suite.run(types.Determinism{},
+		`class Day extends Date {}; new Day() instanceof Day && new Day().getTime() === 0`))
+}
+
+func (suite *DeterministicTestSuite) TestNotDeterministic() {
+	r, err := NewRunner("test.js")
+	suite.Require().NoError(err)
+	defer r.Close()
+	suite.ErrorContains(r.Seed(types.Determinism{}), "not deterministic")
+	val, err := r.RunScript(context.Background(), `Date.now() > 1700000000000`)
+	suite.Require().NoError(err)
+	suite.Equal("true", val.String())
+}
@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+var ErrUnknownScript = fmt.Errorf("unknown script")
+
+// compiledScript is a script registered by Compile.
+type compiledScript struct {
+	code string
+	// script is nil once the runner is reset, until it is compiled again.
+	script *v8.UnboundScript
+}
+
+// Compile compiles code without running it, and registers it under name, replacing the
+// script previously registered under the same name. It returns the syntax errors of code.
+// The script is then run by RunCompiled, even after the runner is reset.
+func (r *Runner) Compile(ctx context.Context, name, code string) error {
+	if r.closed {
+		return fmt.Errorf("runner is closed")
+	}
+	s := &compiledScript{code: code}
+	_, err := r.execute(ctx, func() (*v8.Value, error) {
+		return nil, r.compileHandle(name, s)
+	})
+	if err != nil {
+		return err
+	}
+	if r.handles == nil {
+		r.handles = make(map[string]*compiledScript)
+	}
+	r.handles[name] = s
+	return nil
+}
+
+// RunCompiled runs the script registered under name by Compile, like RunScript.
+func (r *Runner) RunCompiled(ctx context.Context, name string) (*v8.Value, error) {
+	if r.closed {
+		return nil, fmt.Errorf("runner is closed")
+	}
+	s, ok := r.handles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScript, name)
+	}
+	return r.execute(ctx, func() (*v8.Value, error) {
+		if s.script == nil {
+			if err := r.compileHandle(name, s); err != nil {
+				return nil, err
+			}
+		}
+		val, err := s.script.Run(r.codeCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run script because: %w", err)
+		}
+		return val, nil
+	})
+}
+
+// compileHandle compiles the script registered under name, through the script cache if any.
+func (r *Runner) compileHandle(name string, s *compiledScript) error {
+	defer r.timeCompile(time.Now())
+	origin := name + ".js"
+	if r.scripts != nil {
+		cached, err := r.scripts.compile(r.vm, origin, s.code)
+		if err != nil {
+			return fmt.Errorf("failed to compile script because: %w", err)
+		}
+		s.script = cached.script
+		return nil
+	}
+	script, err := r.vm.CompileUnboundScript(s.code, origin, v8.CompileOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to compile script because: %w", err)
+	}
+	s.script = script
+	return nil
+}
@@ -0,0 +1,33 @@
+package runner
+
+import (
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// newIsolate creates the isolate of a runner with the given config. Its heap is limited to
+// heapSizeMB, 0 means the default limit, and if headroomMB is not 0, the execution is
+// terminated once the heap reaches the limit instead of V8 aborting the process, see
+// NearHeapLimitOption. The isolate boots from the snapshot of the config, if any.
+func newIsolate(c config) *v8.Isolate {
+	var options []v8.IsolateOption
+	if c.heapSizeMB > 0 {
+		options = append(options,
+			v8.ResourceConstraints{MaxHeapSize: uint64(c.heapSizeMB) << 20},
+			v8.NearHeapLimit{Headroom: uint64(c.headroomMB) << 20},
+		)
+	}
+	if c.snapshot != nil {
+		options = append(options, v8.StartupSnapshot{Blob: c.snapshot.blob})
+	}
+	return v8.NewIsolate(options...)
+}
+
+// newContext creates a context in vm. If noCodeGeneration is set, eval and the Function
+// constructors of the context throw an EvalError instead of compiling code from strings.
+func newContext(vm *v8.Isolate, noCodeGeneration bool) *v8.Context {
+	ctx := v8.NewContext(vm)
+	if noCodeGeneration {
+		ctx.AllowCodeGenerationFromStrings(false)
+	}
+	return ctx
+}
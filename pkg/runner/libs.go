@@ -1,15 +1,42 @@
 package runner
 
 import (
-	v8 "github.com/stumble/v8go"
+	"errors"
+
 	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
 )
 
+// errCodes maps the errors the caller may want to handle to their codes.
+var errCodes = []struct {
+	err  error
+	code types.ErrCode
+}{
+	{ErrorTimeout, types.ErrCodeTimeout},
+	{ErrStreamTooLarge, types.ErrCodeStreamTooLarge},
+	{ErrCodeTooLarge, types.ErrCodeCodeTooLarge},
+	{ErrResultTooLarge, types.ErrCodeResultTooLarge},
+	{ErrCPUBudgetExceeded, types.ErrCodeCPUBudgetExceeded},
+	{ErrOutOfMemory, types.ErrCodeOutOfMemory},
+	{ErrUnknownScript, types.ErrCodeUnknownScript},
+	{ErrModuleNotFound, types.ErrCodeModuleNotFound},
+}
+
+func errCode(err error) types.ErrCode {
+	for _, c := range errCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
+
 func errResult(id string, err error) types.RunCodeResponse {
 	errStr := err.Error()
 	return types.RunCodeResponse{
-		ID:    id,
-		Error: &errStr,
+		ID:        id,
+		Error:     &errStr,
+		ErrorCode: errCode(err),
 	}
 }
 
@@ -29,3 +56,21 @@ func jsonResult(id string, codeCtx *v8.Context, val *v8.Value) types.RunCodeResp
 		Result: &jsonStr,
 	}
 }
+
+func itemErr(err error) types.ItemResult {
+	errStr := err.Error()
+	return types.ItemResult{
+		Error:     &errStr,
+		ErrorCode: errCode(err),
+	}
+}
+
+func itemJSON(codeCtx *v8.Context, val *v8.Value) types.ItemResult {
+	jsonStr, err := v8.JSONStringify(codeCtx, val)
+	if err != nil {
+		return itemErr(err)
+	}
+	return types.ItemResult{
+		Result: &jsonStr,
+	}
+}
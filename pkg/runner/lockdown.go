@@ -0,0 +1,68 @@
+package runner
+
+import (
+	_ "embed"
+	"fmt"
+
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// lockdown is the script applying the restrictions of LockdownOption.
+//
+//go:embed lockdown.js
+var lockdown string
+
+// LockdownOption restricts the language surface of the context, for untrusted scripts.
+// The restrictions apply to the scripts of the requests, but not to the bootstrap script of
+// the snapshot, the polyfills and the deterministic APIs, which are defined before.
+type LockdownOption struct {
+	// NoCodeGeneration makes eval, new Function and the constructors of the async and
+	// generator functions throw an EvalError instead of compiling code from strings.
+	NoCodeGeneration bool
+	// NoWebAssembly removes WebAssembly.
+	NoWebAssembly bool
+	// NoSharedMemory removes SharedArrayBuffer and Atomics. A shared WebAssembly.Memory
+	// still exposes a SharedArrayBuffer, unless NoWebAssembly is set too.
+	NoSharedMemory bool
+	// FreezeIntrinsics freezes the objects reachable from the global object, e.g. the
+	// built-in constructors and their prototypes, so that a script cannot tamper with them.
+	// The global object itself is not frozen, so that scripts can still define globals.
+	// The common overrides like Foo.prototype.toString = ... or this.name = ... in an
+	// Error subclass keep working, but assigning a property inherited from another frozen
+	// object fails, e.g. an own push property of an array.
+	FreezeIntrinsics bool
+}
+
+func (o LockdownOption) apply(c *config) error {
+	c.lockdown = o
+	return nil
+}
+
+// applyLockdown applies the restrictions of LockdownOption to the context, but the code
+// generation from strings which is disallowed when the context is created.
+func (r *Runner) applyLockdown() error {
+	o := r.config.lockdown
+	if !o.NoWebAssembly && !o.NoSharedMemory && !o.FreezeIntrinsics {
+		return nil
+	}
+	val, err := r.codeCtx.RunScript(lockdown, "lockdown.js")
+	if err != nil {
+		return fmt.Errorf("failed to lock down because: %w", err)
+	}
+	fn, err := val.AsFunction()
+	if err != nil {
+		return fmt.Errorf("failed to lock down because: %w", err)
+	}
+	args := make([]v8.Valuer, 0, 3)
+	for _, arg := range []bool{o.NoWebAssembly, o.NoSharedMemory, o.FreezeIntrinsics} {
+		val, err := v8.NewValue(r.vm, arg)
+		if err != nil {
+			return err
+		}
+		args = append(args, val)
+	}
+	if _, err := fn.Call(v8.Undefined(r.vm), args...); err != nil {
+		return fmt.Errorf("failed to lock down because: %w", err)
+	}
+	return nil
+}
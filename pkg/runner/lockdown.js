@@ -0,0 +1,87 @@
+// Restricts the language surface of the context, see LockdownOption.
+// The script evaluates to a function applying the restrictions.
+(function (noWebAssembly, noSharedMemory, freezeIntrinsics) {
+  "use strict";
+
+  if (noWebAssembly) {
+    delete globalThis.WebAssembly;
+  }
+  if (noSharedMemory) {
+    delete globalThis.SharedArrayBuffer;
+    delete globalThis.Atomics;
+  }
+  if (!freezeIntrinsics) {
+    return;
+  }
+
+  // The data properties that scripts commonly override by assignment on objects inheriting
+  // them, e.g. Foo.prototype.toString = ... or this.name = ... in an Error subclass. Once
+  // frozen, such an assignment would fail because the inherited property is read-only, so
+  // they are turned into accessors which define the property on the object assigned instead.
+  const errors = [Error, EvalError, RangeError, ReferenceError, SyntaxError, TypeError, URIError];
+  if (typeof AggregateError === "function") {
+    errors.push(AggregateError);
+  }
+  const overridable = [
+    [Object.prototype, Object.getOwnPropertyNames(Object.prototype)],
+    [Function.prototype, ["constructor", "toString"]],
+    ...errors.map((e) => [e.prototype, ["constructor", "name", "message", "toString"]]),
+  ];
+  for (const [proto, names] of overridable) {
+    for (const name of names) {
+      const desc = Object.getOwnPropertyDescriptor(proto, name);
+      if (!desc || !("value" in desc)) {
+        continue;
+      }
+      const value = desc.value;
+      Object.defineProperty(proto, name, {
+        get() {
+          return value;
+        },
+        set(v) {
+          if (this === proto) {
+            throw new TypeError(`Cannot assign to read only property '${name}' of object`);
+          }
+          Object.defineProperty(this, name, { value: v, writable: true, enumerable: true, configurable: true });
+        },
+        enumerable: desc.enumerable,
+        configurable: false,
+      });
+    }
+  }
+
+  // The intrinsics that are not reachable from the properties of the global object.
+  const hidden = [
+    Object.getPrototypeOf(function* () {}),
+    Object.getPrototypeOf(async function () {}),
+    Object.getPrototypeOf(async function* () {}),
+    Object.getPrototypeOf([][Symbol.iterator]()),
+    Object.getPrototypeOf(new Map()[Symbol.iterator]()),
+    Object.getPrototypeOf(new Set()[Symbol.iterator]()),
+    Object.getPrototypeOf(""[Symbol.iterator]()),
+    Object.getPrototypeOf(/a/[Symbol.matchAll]("")),
+    Object.getPrototypeOf(Int8Array),
+    Object.getOwnPropertyDescriptor(Function.prototype, "caller")?.get,
+  ];
+
+  // freeze the objects reachable from the global object, through their properties and prototypes.
+  const frozen = new Set();
+  const pending = [...hidden];
+  for (const name of Reflect.ownKeys(globalThis)) {
+    const desc = Object.getOwnPropertyDescriptor(globalThis, name);
+    pending.push(desc.value, desc.get, desc.set);
+  }
+  while (pending.length > 0) {
+    const obj = pending.pop();
+    if ((typeof obj !== "object" && typeof obj !== "function") || obj === null || obj === globalThis || frozen.has(obj)) {
+      continue;
+    }
+    frozen.add(obj);
+    Object.freeze(obj);
+    pending.push(Object.getPrototypeOf(obj));
+    for (const key of Reflect.ownKeys(obj)) {
+      const desc = Object.getOwnPropertyDescriptor(obj, key);
+      pending.push(desc.value, desc.get, desc.set);
+    }
+  }
+});
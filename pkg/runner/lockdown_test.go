@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LockdownTestSuite struct {
+	suite.Suite
+}
+
+func TestLockdownTestSuite(t *testing.T) {
+	suite.Run(t, new(LockdownTestSuite))
+}
+
+func (suite *LockdownTestSuite) newRunner(o LockdownOption) *Runner {
+	r, err := NewRunner("test.js", o)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(r.Close)
+	return r
+}
+
+func (suite *LockdownTestSuite) run(r *Runner, script string) (string, error) {
+	val, err := r.RunScript(context.Background(), script)
+	if err != nil {
+		return "", err
+	}
+	return val.String(), nil
+}
+
+func (suite *LockdownTestSuite) TestNoCodeGeneration() {
+	r := suite.newRunner(LockdownOption{NoCodeGeneration: true})
+	for _, script := range []string{
+		`eval("1 + 1")`,
+		`(0, eval)("1 + 1")`,
+		`new Function("return 1")()`,
+		`Function("return 1")()`,
+		`(function () {}).constructor("return 1")()`,
+		`(async function () {}).constructor("return 1")`,
+		`(function* () {}).constructor("yield 1")`,
+		`(async function* () {}).constructor("yield 1")`,
+	} {
+		_, err := suite.run(r, script)
+		suite.ErrorContains(err, "EvalError: Code generation from strings disallowed", script)
+	}
+	// scripts, modules and functions are still compiled.
+	res, err := suite.run(r, `function f() { return 2; }; f()`)
+	suite.NoError(err)
+	suite.Equal("2", res)
+
+	// other runners are not affected.
+	open := suite.newRunner(LockdownOption{})
+	res, err = suite.run(open, `eval("1 + 1")`)
+	suite.NoError(err)
+	suite.Equal("2", res)
+}
+
+func (suite *LockdownTestSuite) TestNoWebAssembly() {
+	r := suite.newRunner(LockdownOption{NoWebAssembly: true})
+	res, err := suite.run(r, `typeof WebAssembly`)
+	suite.NoError(err)
+	suite.Equal("undefined", res)
+	_, err = suite.run(r, `WebAssembly.compile`)
+	suite.ErrorContains(err, "WebAssembly is not defined")
+
+	open := suite.newRunner(LockdownOption{})
+	res, err = suite.run(open, `typeof WebAssembly`)
+	suite.NoError(err)
+	suite.Equal("object", res)
+}
+
+func (suite *LockdownTestSuite) TestNoSharedMemory() {
+	r := suite.newRunner(LockdownOption{NoSharedMemory: true, NoWebAssembly: true})
+	res, err := suite.run(r, `[typeof SharedArrayBuffer, typeof Atomics, "SharedArrayBuffer" in globalThis].join()`)
+	suite.NoError(err)
+	suite.Equal("undefined,undefined,false", res)
+	_, err = suite.run(r, `new SharedArrayBuffer(8)`)
+	suite.ErrorContains(err, "SharedArrayBuffer is not defined")
+}
+
+func (suite *LockdownTestSuite) TestFreezeIntrinsics() {
+	r := suite.newRunner(LockdownOption{FreezeIntrinsics: true})
+	res, err := suite.run(r, `[
+		Object.isFrozen(Object.prototype), Object.isFrozen(Array.prototype), Object.isFrozen(Array),
+		Object.isFrozen(JSON), Object.isFrozen(Math), Object.isFrozen(Promise.prototype),
+		Object.isFrozen(Object.getPrototypeOf(function* () {})),
+		Object.isFrozen(Object.getPrototypeOf([][Symbol.iterator]())),
+		Object.isFrozen(Object.getPrototypeOf(Int8Array).prototype),
+		Object.isFrozen(globalThis),
+	].join()`)
+	suite.NoError(err)
+	suite.Equal("true,true,true,true,true,true,true,true,true,false", res)
+
+	for _, script := range []string{
+		`"use strict"; Array.prototype.push = () => 0`,
+		`"use strict"; Object.prototype.polluted = true`,
+		`"use strict"; JSON.parse = () => 0`,
+		`"use strict"; delete Math.max`,
+		`Object.defineProperty(String.prototype, "trim", {value: () => ""})`,
+	} {
+		_, err := suite.run(r, script)
+		suite.ErrorContains(err, "TypeError", script)
+	}
+	res, err = suite.run(r, `Array.prototype.push = () => 0; Object.prototype.polluted = 1; [[].push(1), ({}).polluted].join()`)
+	suite.NoError(err)
+	suite.Equal("1,", res)
+
+	// common overrides still work, and scripts can define globals.
+	res, err = suite.run(r, `"use strict";
+		function Point() {}
+		Point.prototype.toString = function () { return "point"; };
+		class MyError extends Error { constructor(m) { super(m); this.name = "MyError"; } }
+		var defined = 1;
+		[String(new Point()), String(new MyError("m")), defined, Object.prototype.toString.call([])].join()`)
+	suite.NoError(err)
+	suite.Equal("point,MyError: m,1,[object Array]", res)
+	_, err = suite.run(r, `"use strict"; Object.prototype.toString = () => ""`)
+	suite.ErrorContains(err, "TypeError")
+}
+
+func (suite *LockdownTestSuite) TestWithPolyfills() {
+	r, err := NewRunner("test.js", PolyfillsOption{}, DeterministicOption{},
+		LockdownOption{NoCodeGeneration: true, NoWebAssembly: true, NoSharedMemory: true, FreezeIntrinsics: true})
+	suite.Require().NoError(err)
+	defer r.Close()
+	res, err := suite.run(r, `[Object.isFrozen(URL.prototype), atob(btoa("a")), Date.now(), new TextDecoder().decode(new Uint8Array([97]))].join()`)
+	suite.NoError(err)
+	suite.Equal("true,a,0,a", res)
+}
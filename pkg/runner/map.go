@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// mapResult evaluates req.Code to a function and calls it with each of req.Inputs.
+// A failing item does not fail the request, its error is reported in the item result.
+func (r *ReaderRunner) mapResult(
+	ctx context.Context,
+	runner *Runner,
+	req types.RunCodeRequest,
+) types.RunCodeResponse {
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	val, err := runner.RunScript(ctx, req.Code)
+	if err != nil {
+		return errResult(req.ID, err)
+	}
+	fn, err := val.AsFunction()
+	if err != nil {
+		return errResult(req.ID, fmt.Errorf("failed to map because: %w", err))
+	}
+
+	items := make([]types.ItemResult, len(req.Inputs))
+	size := 0
+	for i, input := range req.Inputs {
+		if i > 0 && items[i-1].ErrorCode == types.ErrCodeOutOfMemory {
+			// the runner has been reset, fn belongs to the previous isolate.
+			items[i] = itemErr(fmt.Errorf("%w: item not started", ErrOutOfMemory))
+		} else {
+			items[i] = callItem(ctx, runner, fn, input, req.ItemTimeout)
+		}
+		if items[i].Result != nil {
+			size += len(*items[i].Result)
+		}
+		if items[i].Error != nil {
+			size += len(*items[i].Error)
+		}
+		if err := r.checkResultSize(size); err != nil {
+			return errResult(req.ID, err)
+		}
+	}
+	return types.RunCodeResponse{
+		ID:    req.ID,
+		Items: items,
+	}
+}
+
+func callItem(
+	ctx context.Context,
+	runner *Runner,
+	fn *v8.Function,
+	input string,
+	timeout time.Duration,
+) types.ItemResult {
+	if ctx.Err() != nil {
+		return itemErr(fmt.Errorf("%w: item not started", ErrorTimeout))
+	}
+	arg, err := v8.JSONParse(runner.CodeCtx(), input)
+	if err != nil {
+		return itemErr(fmt.Errorf("failed to parse input because: %w", err))
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	val, err := runner.Call(ctx, fn, arg)
+	if err != nil {
+		return itemErr(err)
+	}
+	return itemJSON(runner.CodeCtx(), val)
+}
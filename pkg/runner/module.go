@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+var ErrModuleNotFound = fmt.Errorf("module not found")
+
+// modules is the registry of the ES modules of a runner, see RegisterModules.
+type modules struct {
+	// sources are the sources of the registered modules, by normalized specifier.
+	sources map[string]string
+	// loaded are the modules compiled in the context of the runner, by specifier.
+	loaded map[string]*v8.Module
+}
+
+// RegisterModules registers the ES modules of sources by specifier, replacing the modules
+// registered before under the same specifiers. Modules import each other by specifier, a
+// relative specifier like "./util.js" is resolved against the specifier of the importing
+// module. A module is evaluated once, the first time it is imported, so registering
+// modules discards the modules evaluated before, which are evaluated again when imported.
+// Modules are evaluated by V8, and can also be imported with import() by modules and
+// scripts.
+func (r *Runner) RegisterModules(sources map[string]string) {
+	if r.modules == nil {
+		r.modules = &modules{sources: make(map[string]string)}
+		r.codeCtx.SetModuleImporter(r.importDynamic)
+	}
+	for specifier, source := range sources {
+		r.modules.sources[normalizeSpecifier(specifier)] = source
+	}
+	r.modules.loaded = nil
+}
+
+// CallModule imports the module registered as specifier, and calls the function it
+// exports under name ("default" for the default export) with args.
+// Timeouts are handled like Call.
+func (r *Runner) CallModule(
+	ctx context.Context,
+	specifier, name string,
+	args ...v8.Valuer,
+) (*v8.Value, error) {
+	if r.closed {
+		return nil, fmt.Errorf("runner is closed")
+	}
+	return r.execute(ctx, func() (*v8.Value, error) {
+		exports, err := r.importModule(normalizeSpecifier(specifier))
+		if err != nil {
+			return nil, err
+		}
+		val, err := exports.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call module because: %w", err)
+		}
+		fn, err := val.AsFunction()
+		if err != nil {
+			return nil, fmt.Errorf("failed to call module because: %s does not export a function %s", specifier, name)
+		}
+		val, err = fn.Call(v8.Undefined(r.vm), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call module because: %w", err)
+		}
+		return val, nil
+	})
+}
+
+// resolve returns the specifier and the source of the registered module designated by
+// specifier, trying the usual extensions of modules.
+func (m *modules) resolve(specifier string) (string, string, bool) {
+	if m == nil {
+		return "", "", false
+	}
+	for _, candidate := range []string{specifier, specifier + ".js", specifier + ".mjs", specifier + "/index.js"} {
+		if source, ok := m.sources[candidate]; ok {
+			return candidate, source, true
+		}
+	}
+	return "", "", false
+}
+
+// importModule returns the namespace of the module, evaluating it if it is imported for
+// the first time. The evaluation fails if the module awaits a promise that is not settled
+// once the microtasks have run.
+func (r *Runner) importModule(specifier string) (*v8.Object, error) {
+	m, err := r.loadModule(specifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Instantiate(); err != nil {
+		return nil, fmt.Errorf("failed to import module because: %w", err)
+	}
+	promise, err := m.Evaluate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to import module because: %w", err)
+	}
+	if promise.State() == v8.Pending {
+		return nil, fmt.Errorf("failed to import module because: %s awaits a promise that is not settled", specifier)
+	}
+	return m.Namespace(), nil
+}
+
+// loadModule returns the registered module designated by specifier, compiled and linked
+// to the modules it imports, which are loaded too. The module is registered as loaded
+// before the modules it imports, so that modules importing each other are linked to the
+// same module.
+func (r *Runner) loadModule(specifier string) (*v8.Module, error) {
+	resolved, source, ok := r.modules.resolve(specifier)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrModuleNotFound, specifier)
+	}
+	if m, ok := r.modules.loaded[resolved]; ok {
+		return m, nil
+	}
+
+	start := time.Now()
+	m, err := r.codeCtx.CompileModule(source, resolved)
+	r.timeCompile(start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import module because: %w", err)
+	}
+	if r.modules.loaded == nil {
+		r.modules.loaded = make(map[string]*v8.Module)
+	}
+	r.modules.loaded[resolved] = m
+	for _, request := range m.Requests() {
+		imported, err := r.loadModule(resolveSpecifier(resolved, request))
+		if err != nil {
+			delete(r.modules.loaded, resolved)
+			return nil, err
+		}
+		m.Link(request, imported)
+	}
+	return m, nil
+}
+
+// importDynamic loads and instantiates the module imported with import() by referrer, the
+// specifier of a module or the file name of a script.
+func (r *Runner) importDynamic(referrer, specifier string) (*v8.Module, error) {
+	m, err := r.loadModule(resolveSpecifier(referrer, specifier))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Instantiate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// resolveSpecifier resolves the specifier imported by the module referrer to the
+// specifier of a registered module. Relative specifiers are resolved against the
+// directory of referrer, like paths.
+func resolveSpecifier(referrer, specifier string) string {
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+		specifier = path.Join(path.Dir(referrer), specifier)
+	}
+	return normalizeSpecifier(specifier)
+}
+
+// normalizeSpecifier cleans a specifier, so that "./lib/a.js", "/lib/a.js" and
+// "lib/a.js" designate the same module.
+func normalizeSpecifier(specifier string) string {
+	return strings.TrimPrefix(path.Clean(specifier), "/")
+}
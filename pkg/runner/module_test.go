@@ -0,0 +1,207 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+type ModuleTestSuite struct {
+	suite.Suite
+	runner *Runner
+}
+
+func TestModuleTestSuite(t *testing.T) {
+	suite.Run(t, new(ModuleTestSuite))
+}
+
+func (suite *ModuleTestSuite) SetupTest() {
+	r, err := NewRunner("test.js")
+	suite.Require().NoError(err)
+	suite.runner = r
+}
+
+func (suite *ModuleTestSuite) TearDownTest() {
+	suite.runner.Close()
+}
+
+func (suite *ModuleTestSuite) call(specifier, name string) string {
+	val, err := suite.runner.CallModule(context.Background(), specifier, name)
+	suite.Require().NoError(err)
+	return val.String()
+}
+
+// settled calls the function like call, and returns the result of the promise it returns.
+func (suite *ModuleTestSuite) settled(specifier, name string) string {
+	val, err := suite.runner.CallModule(context.Background(), specifier, name)
+	suite.Require().NoError(err)
+	suite.runner.CodeCtx().PerformMicrotaskCheckpoint()
+	promise, err := val.AsPromise()
+	suite.Require().NoError(err)
+	suite.Require().Equal(v8.Fulfilled, promise.State())
+	return promise.Result().String()
+}
+
+func (suite *ModuleTestSuite) TestImportExport() {
+	suite.runner.RegisterModules(map[string]string{
+		"lib/math.js": `
+			export const PI = 3, E = 2
+			export let counter = 0;
+			export function inc() { return ++counter; }
+			export default function (a, b) { return a + b; }
+			export class Point { constructor(x) { this.x = x; } }
+			const hidden = 1, shown = 2;
+			export { shown as visible };
+			export const { a, b: [c] } = { a: 10, b: [20] };
+		`,
+		"lib/index.js": `
+			export * from "./math.js";
+			export * as math from './math';
+			export { default as add, PI as pi } from "./math.js";
+		`,
+		"main.js": `
+			import add, { PI, inc, counter, Point, visible as v, a, c } from "./lib/math.js";
+			import * as lib from "lib";
+			import "./side.js";
+			export default function () {
+				inc();
+				return [add(PI, 1), counter, lib.counter, new Point(v).x, a, c, lib.pi, lib.math.E, lib.add(1, 1), globalThis.side].join(",");
+			}
+			export const regex = () => /import x from "y"/.source;
+			export const text = () => ` + "`export ${PI} {`" + `;
+		`,
+		"side.js": `globalThis.side = "side";`,
+	})
+	// imported bindings are live
+	suite.Equal("4,1,1,2,10,20,3,2,2,side", suite.call("main.js", "default"))
+	suite.Equal(`import x from "y"`, suite.call("./main.js", "regex"))
+	suite.Equal("export 3 {", suite.call("/main.js", "text"))
+}
+
+func (suite *ModuleTestSuite) TestImportMethod() {
+	suite.runner.RegisterModules(map[string]string{
+		"methods.js": `
+			const o = { import(x) { return x; }, export: 1 };
+			class C { import() { return 3; } static import(x) { return x; } }
+			export function f() { return o.import(2) + o.export + new C().import() + C.import(4); }
+		`,
+	})
+	suite.Equal("10", suite.call("methods.js", "f"))
+}
+
+func (suite *ModuleTestSuite) TestRegexAfterParen() {
+	suite.runner.RegisterModules(map[string]string{
+		"regex.js": `export function f() { let s = "x{"; if (s) /[{"]/.test(s); return 1; }`,
+	})
+	suite.Equal("1", suite.call("regex.js", "f"))
+}
+
+func (suite *ModuleTestSuite) TestHoisting() {
+	suite.runner.RegisterModules(map[string]string{
+		// the import is declared after its use, and b.js is evaluated first
+		"a.js": `globalThis.order = (globalThis.order || "") + "a"; export const f = () => x + order; import { x } from "./b.js";`,
+		"b.js": `globalThis.order = (globalThis.order || "") + "b"; export const x = "x";`,
+	})
+	suite.Equal("xba", suite.call("a.js", "f"))
+}
+
+func (suite *ModuleTestSuite) TestCycle() {
+	suite.runner.RegisterModules(map[string]string{
+		"a.js": `import { b } from "./b.js"; export function a() { return "a"; } export function run() { return b(); }`,
+		"b.js": `import * as modA from "./a.js"; export function b() { return modA.a() + "b"; }`,
+	})
+	suite.Equal("ab", suite.call("a.js", "run"))
+}
+
+func (suite *ModuleTestSuite) TestEvaluatedOnce() {
+	suite.runner.RegisterModules(map[string]string{
+		"counter.js": `let n = 0; export function next() { return ++n; }`,
+	})
+	suite.Equal("1", suite.call("counter.js", "next"))
+	suite.Equal("2", suite.call("counter.js", "next"))
+	// registering modules evaluates them again
+	suite.runner.RegisterModules(map[string]string{"other.js": `export const x = 1;`})
+	suite.Equal("1", suite.call("counter.js", "next"))
+}
+
+func (suite *ModuleTestSuite) TestErrors() {
+	suite.runner.RegisterModules(map[string]string{
+		"missing.js": `import { x } from "./nowhere.js"; export function f() { return x; }`,
+		"nested.js":  `import { f } from "./missing.js"; export { f };`,
+		"dynamic.js": `export async function f() { try { await import("./nowhere.js"); } catch (e) { return e.message; } }`,
+		"throws.js":  `export function f() { throw new Error("boom"); }`,
+		"strict.js":  `export function f() { undeclared = 1; }`,
+	})
+	_, err := suite.runner.CallModule(context.Background(), "missing.js", "f")
+	suite.ErrorIs(err, ErrModuleNotFound)
+	suite.EqualError(err, "module not found: nowhere.js")
+	_, err = suite.runner.CallModule(context.Background(), "nested.js", "f")
+	suite.ErrorIs(err, ErrModuleNotFound)
+	suite.EqualError(err, "module not found: nowhere.js")
+	_, err = suite.runner.CallModule(context.Background(), "unknown.js", "f")
+	suite.ErrorIs(err, ErrModuleNotFound)
+	suite.Equal("module not found: nowhere.js", suite.settled("dynamic.js", "f"))
+	_, err = suite.runner.CallModule(context.Background(), "throws.js", "f")
+	suite.ErrorContains(err, "boom")
+	_, err = suite.runner.CallModule(context.Background(), "throws.js", "g")
+	suite.ErrorContains(err, "does not export a function g")
+	// modules are strict
+	_, err = suite.runner.CallModule(context.Background(), "strict.js", "f")
+	suite.ErrorContains(err, "undeclared is not defined")
+}
+
+func (suite *ModuleTestSuite) TestLineNumbers() {
+	suite.runner.RegisterModules(map[string]string{
+		"lines.js":  "import {\n  x\n} from './x.js';\nexport function f() {\n  throw new Error('line 5');\n}\n",
+		"x.js":      "export const x = 1;",
+		"syntax.js": "export const a = 1;\n\nexport const = 3;\n",
+	})
+	_, err := suite.runner.CallModule(context.Background(), "lines.js", "f")
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "line 5")
+	var jsErr *v8.JSError
+	suite.Require().ErrorAs(err, &jsErr)
+	suite.Contains(jsErr.StackTrace, "lines.js:5")
+	_, err = suite.runner.CallModule(context.Background(), "syntax.js", "a")
+	suite.Require().ErrorAs(err, &jsErr)
+	suite.Equal("syntax.js:3:14", jsErr.Location)
+}
+
+func (suite *ModuleTestSuite) TestDynamicImport() {
+	suite.runner.RegisterModules(map[string]string{
+		"lib/lazy.js": `export const answer = 42;`,
+		"lib/main.js": `export async function f() { const lazy = await import("./lazy.js"); return lazy.answer; }`,
+	})
+	suite.Equal("42", suite.settled("lib/main.js", "f"))
+	// scripts import modules too, relative to the file name of the runner
+	val, err := suite.runner.RunScript(context.Background(), `import("./lib/lazy.js").then((m) => globalThis.answer = m.answer)`)
+	suite.Require().NoError(err)
+	suite.runner.CodeCtx().PerformMicrotaskCheckpoint()
+	promise, err := val.AsPromise()
+	suite.Require().NoError(err)
+	suite.Equal(v8.Fulfilled, promise.State())
+	suite.Equal("42", promise.Result().String())
+}
+
+func (suite *ModuleTestSuite) TestImportMeta() {
+	suite.runner.RegisterModules(map[string]string{
+		"./lib/meta.js": `export const url = () => import.meta.url;`,
+	})
+	suite.Equal("lib/meta.js", suite.call("lib/meta.js", "url"))
+}
+
+func (suite *ModuleTestSuite) TestTopLevelAwait() {
+	suite.runner.RegisterModules(map[string]string{
+		"config.js":  `export const config = await Promise.resolve({ limit: 3 });`,
+		"main.js":    `import { config } from "./config.js"; export const limit = () => config.limit;`,
+		"pending.js": `await new Promise(() => {}); export const f = () => 1;`,
+		"rejects.js": `await Promise.reject(new Error("rejected")); export const f = () => 1;`,
+	})
+	suite.Equal("3", suite.call("main.js", "limit"))
+	_, err := suite.runner.CallModule(context.Background(), "pending.js", "f")
+	suite.ErrorContains(err, "pending.js awaits a promise that is not settled")
+	_, err = suite.runner.CallModule(context.Background(), "rejects.js", "f")
+	suite.ErrorContains(err, "rejected")
+}
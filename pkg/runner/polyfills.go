@@ -0,0 +1,128 @@
+package runner
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// polyfills is the script installing the polyfills of PolyfillsOption.
+//
+//go:embed polyfills.js
+var polyfills string
+
+// PolyfillsOption defines the web platform APIs that scripts commonly expect and that a
+// bare V8 context lacks: TextEncoder and TextDecoder (UTF-8 only), atob and btoa, URL and
+// URLSearchParams, structuredClone, and the DOMException they throw. The globals already
+// defined by the context are kept. They are implemented in JavaScript, but for the
+// parsing of URLs which is done by net/url, so URL follows RFC 3986 rather than the
+// WHATWG URL Standard for the URLs on which they differ, e.g. it does not apply IDNA.
+type PolyfillsOption struct{}
+
+func (o PolyfillsOption) apply(c *config) error {
+	c.polyfills = true
+	return nil
+}
+
+// definePolyfills defines the polyfills in the context of the runner, if enabled.
+func (r *Runner) definePolyfills() error {
+	if !r.config.polyfills {
+		return nil
+	}
+	val, err := r.codeCtx.RunScript(polyfills, "polyfills.js")
+	if err != nil {
+		return fmt.Errorf("failed to define polyfills because: %w", err)
+	}
+	install, err := val.AsFunction()
+	if err != nil {
+		return fmt.Errorf("failed to define polyfills because: %w", err)
+	}
+	parse := v8.NewFunctionTemplate(r.vm, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		args := info.Args()
+		val, _ := v8.NewValue(r.vm, parseURL(args[0].String(), args[1].String(), args[2].Boolean()))
+		return val
+	})
+	if _, err := install.Call(v8.Undefined(r.vm), parse.GetFunction(r.codeCtx)); err != nil {
+		return fmt.Errorf("failed to define polyfills because: %w", err)
+	}
+	return nil
+}
+
+// urlComponents are the components of a URL, as exposed by the URL polyfill.
+type urlComponents struct {
+	Protocol string `json:"protocol"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Hostname string `json:"hostname"`
+	Port     string `json:"port"`
+	Pathname string `json:"pathname"`
+	Search   string `json:"search"`
+	Hash     string `json:"hash"`
+}
+
+// defaultPorts are the default ports of the special schemes, which are omitted from URLs.
+var defaultPorts = map[string]string{
+	"http": "80", "https": "443", "ws": "80", "wss": "443", "ftp": "21",
+}
+
+// parseURL returns the JSON encoded components of input resolved against base,
+// or "" if it is not a valid absolute URL.
+func parseURL(input, base string, hasBase bool) string {
+	ref, err := url.Parse(strings.TrimSpace(input))
+	if err != nil {
+		return ""
+	}
+	u := ref
+	if hasBase {
+		b, err := url.Parse(strings.TrimSpace(base))
+		if err != nil || !b.IsAbs() {
+			return ""
+		}
+		u = b.ResolveReference(ref)
+	} else if u.IsAbs() {
+		u = (&url.URL{}).ResolveReference(u) // removes the dot segments of the path
+	}
+	if !u.IsAbs() {
+		return ""
+	}
+
+	_, special := defaultPorts[u.Scheme]
+	special = special || u.Scheme == "file"
+	c := urlComponents{
+		Protocol: u.Scheme + ":",
+		Hostname: strings.ToLower(u.Hostname()),
+		Port:     u.Port(),
+	}
+	if special && c.Hostname == "" && u.Scheme != "file" {
+		return ""
+	}
+	if c.Port == defaultPorts[u.Scheme] {
+		c.Port = ""
+	}
+	if u.User != nil {
+		c.Username, c.Password, _ = strings.Cut(u.User.String(), ":")
+	}
+	if u.Opaque != "" {
+		c.Pathname = u.Opaque
+	} else {
+		c.Pathname = u.EscapedPath()
+		if special && c.Pathname == "" {
+			c.Pathname = "/"
+		}
+	}
+	if u.RawQuery != "" || u.ForceQuery {
+		c.Search = "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		c.Hash = "#" + u.EscapedFragment()
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,737 @@
+// Polyfills of the web platform APIs missing from a bare V8 context, see PolyfillsOption.
+// The script evaluates to a function installing them, given the Go function parsing URLs.
+(function (parseURL) {
+  "use strict";
+
+  const define = (name, value) => {
+    if (!(name in globalThis)) {
+      Object.defineProperty(globalThis, name, { value, writable: true, configurable: true });
+    }
+  };
+
+  // DOMException
+
+  class DOMException extends Error {
+    constructor(message = "", name = "Error") {
+      super(message);
+      Object.defineProperty(this, "name", { value: String(name), writable: true, configurable: true });
+    }
+  }
+  define("DOMException", DOMException);
+  const domException = globalThis.DOMException;
+
+  // UTF-8
+
+  // SharedArrayBuffer may be missing from the context.
+  const SharedArrayBufferCtor = typeof SharedArrayBuffer === "function" ? SharedArrayBuffer : ArrayBuffer;
+
+  const toBytes = (input) => {
+    if (input === undefined) {
+      return new Uint8Array(0);
+    }
+    if (input instanceof ArrayBuffer || input instanceof SharedArrayBufferCtor) {
+      return new Uint8Array(input);
+    }
+    if (ArrayBuffer.isView(input)) {
+      return new Uint8Array(input.buffer, input.byteOffset, input.byteLength);
+    }
+    throw new TypeError("The provided value is not of type '(ArrayBuffer or ArrayBufferView)'");
+  };
+
+  // encodeUTF8 calls write with each byte of the UTF-8 encoding of str, lone surrogates are
+  // encoded as U+FFFD. It stops before a code point for which write returns false, and
+  // returns the number of code units of str encoded.
+  const encodeUTF8 = (str, write) => {
+    let i = 0;
+    while (i < str.length) {
+      let cp = str.charCodeAt(i);
+      let units = 1;
+      if (cp >= 0xd800 && cp <= 0xdbff && i + 1 < str.length) {
+        const next = str.charCodeAt(i + 1);
+        if (next >= 0xdc00 && next <= 0xdfff) {
+          cp = 0x10000 + ((cp - 0xd800) << 10) + (next - 0xdc00);
+          units = 2;
+        }
+      }
+      if (cp >= 0xd800 && cp <= 0xdfff) {
+        cp = 0xfffd;
+      }
+      let bytes;
+      if (cp < 0x80) {
+        bytes = [cp];
+      } else if (cp < 0x800) {
+        bytes = [0xc0 | (cp >> 6), 0x80 | (cp & 0x3f)];
+      } else if (cp < 0x10000) {
+        bytes = [0xe0 | (cp >> 12), 0x80 | ((cp >> 6) & 0x3f), 0x80 | (cp & 0x3f)];
+      } else {
+        bytes = [0xf0 | (cp >> 18), 0x80 | ((cp >> 12) & 0x3f), 0x80 | ((cp >> 6) & 0x3f), 0x80 | (cp & 0x3f)];
+      }
+      if (!write(bytes)) {
+        break;
+      }
+      i += units;
+    }
+    return i;
+  };
+
+  const utf8Bytes = (str) => {
+    const out = [];
+    encodeUTF8(str, (bytes) => {
+      out.push(...bytes);
+      return true;
+    });
+    return new Uint8Array(out);
+  };
+
+  // UTF8Decoder is the UTF-8 decoder of the Encoding Standard, which keeps the state of an
+  // incomplete sequence between the chunks of a stream.
+  class UTF8Decoder {
+    constructor(fatal) {
+      this.fatal = fatal;
+      this.reset();
+    }
+
+    reset() {
+      this.needed = 0;
+      this.seen = 0;
+      this.cp = 0;
+      this.lower = 0x80;
+      this.upper = 0xbf;
+    }
+
+    decode(bytes, stream) {
+      let out = "";
+      let chunk = [];
+      const push = (cp) => {
+        chunk.push(cp);
+        if (chunk.length >= 8192) {
+          out += String.fromCodePoint(...chunk);
+          chunk = [];
+        }
+      };
+      const error = () => {
+        if (this.fatal) {
+          this.reset();
+          throw new TypeError("The encoded data was not valid for encoding utf-8");
+        }
+        push(0xfffd);
+      };
+      for (let i = 0; i < bytes.length; i++) {
+        const b = bytes[i];
+        if (this.needed === 0) {
+          if (b <= 0x7f) {
+            push(b);
+          } else if (b >= 0xc2 && b <= 0xdf) {
+            this.needed = 1;
+            this.cp = b & 0x1f;
+          } else if (b >= 0xe0 && b <= 0xef) {
+            if (b === 0xe0) this.lower = 0xa0;
+            if (b === 0xed) this.upper = 0x9f;
+            this.needed = 2;
+            this.cp = b & 0xf;
+          } else if (b >= 0xf0 && b <= 0xf4) {
+            if (b === 0xf0) this.lower = 0x90;
+            if (b === 0xf4) this.upper = 0x8f;
+            this.needed = 3;
+            this.cp = b & 0x7;
+          } else {
+            error();
+          }
+          continue;
+        }
+        if (b < this.lower || b > this.upper) {
+          this.reset();
+          error();
+          i--; // the byte starts the next sequence
+          continue;
+        }
+        this.lower = 0x80;
+        this.upper = 0xbf;
+        this.cp = (this.cp << 6) | (b & 0x3f);
+        if (++this.seen === this.needed) {
+          const cp = this.cp;
+          this.reset();
+          push(cp);
+        }
+      }
+      if (this.needed !== 0 && !stream) {
+        this.reset();
+        error();
+      }
+      return out + String.fromCodePoint(...chunk);
+    }
+  }
+
+  // TextEncoder and TextDecoder
+
+  class TextEncoder {
+    get encoding() {
+      return "utf-8";
+    }
+
+    encode(input = "") {
+      return utf8Bytes(String(input));
+    }
+
+    encodeInto(source, destination) {
+      if (!(destination instanceof Uint8Array)) {
+        throw new TypeError("The provided value is not of type 'Uint8Array'");
+      }
+      let written = 0;
+      const read = encodeUTF8(String(source), (bytes) => {
+        if (written + bytes.length > destination.length) {
+          return false;
+        }
+        destination.set(bytes, written);
+        written += bytes.length;
+        return true;
+      });
+      return { read, written };
+    }
+  }
+  define("TextEncoder", TextEncoder);
+
+  const utf8Labels = ["unicode-1-1-utf-8", "unicode11utf8", "unicode20utf8", "utf-8", "utf8", "x-unicode20utf8"];
+
+  class TextDecoder {
+    #decoder;
+    #ignoreBOM;
+    #bomSeen = false;
+
+    constructor(label = "utf-8", options = {}) {
+      if (!utf8Labels.includes(String(label).trim().toLowerCase())) {
+        throw new RangeError(`The encoding label provided ('${label}') is invalid or not supported.`);
+      }
+      this.#decoder = new UTF8Decoder(Boolean(options.fatal));
+      this.#ignoreBOM = Boolean(options.ignoreBOM);
+    }
+
+    get encoding() {
+      return "utf-8";
+    }
+
+    get fatal() {
+      return this.#decoder.fatal;
+    }
+
+    get ignoreBOM() {
+      return this.#ignoreBOM;
+    }
+
+    decode(input, options = {}) {
+      const stream = Boolean(options.stream);
+      let out = this.#decoder.decode(toBytes(input), stream);
+      if (!this.#ignoreBOM && !this.#bomSeen && out.length > 0) {
+        this.#bomSeen = true;
+        if (out.charCodeAt(0) === 0xfeff) {
+          out = out.slice(1);
+        }
+      }
+      if (!stream) {
+        this.#bomSeen = false;
+      }
+      return out;
+    }
+  }
+  define("TextDecoder", TextDecoder);
+
+  // atob and btoa
+
+  const base64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+  define("btoa", function btoa(data) {
+    if (arguments.length === 0) {
+      throw new TypeError("1 argument required, but only 0 present.");
+    }
+    const str = String(data);
+    let out = "";
+    for (let i = 0; i < str.length; i += 3) {
+      const bytes = [];
+      for (let j = i; j < i + 3 && j < str.length; j++) {
+        const c = str.charCodeAt(j);
+        if (c > 0xff) {
+          throw new domException("Invalid character", "InvalidCharacterError");
+        }
+        bytes.push(c);
+      }
+      const n = (bytes[0] << 16) | ((bytes[1] || 0) << 8) | (bytes[2] || 0);
+      out += base64[(n >> 18) & 63] + base64[(n >> 12) & 63];
+      out += bytes.length > 1 ? base64[(n >> 6) & 63] : "=";
+      out += bytes.length > 2 ? base64[n & 63] : "=";
+    }
+    return out;
+  });
+
+  define("atob", function atob(data) {
+    if (arguments.length === 0) {
+      throw new TypeError("1 argument required, but only 0 present.");
+    }
+    let str = String(data).replace(/[\t\n\f\r ]/g, "");
+    if (str.length % 4 === 0) {
+      str = str.replace(/==?$/, "");
+    }
+    if (str.length % 4 === 1 || /[^A-Za-z0-9+/]/.test(str)) {
+      throw new domException("The string to be decoded is not correctly encoded.", "InvalidCharacterError");
+    }
+    let out = "";
+    let buffer = 0;
+    let bits = 0;
+    for (let i = 0; i < str.length; i++) {
+      buffer = (buffer << 6) | base64.indexOf(str[i]);
+      bits += 6;
+      if (bits >= 8) {
+        bits -= 8;
+        out += String.fromCharCode((buffer >> bits) & 0xff);
+      }
+    }
+    return out;
+  });
+
+  // URLSearchParams
+
+  const formEncode = (str) => {
+    let out = "";
+    for (const b of utf8Bytes(str)) {
+      const c = String.fromCharCode(b);
+      if (/[A-Za-z0-9*\-._]/.test(c)) {
+        out += c;
+      } else if (b === 0x20) {
+        out += "+";
+      } else {
+        out += "%" + b.toString(16).toUpperCase().padStart(2, "0");
+      }
+    }
+    return out;
+  };
+
+  const percentDecode = (str) => {
+    const bytes = [];
+    for (let i = 0; i < str.length; i++) {
+      const c = str.charCodeAt(i);
+      if (c === 0x25 && /^[0-9A-Fa-f]{2}$/.test(str.slice(i + 1, i + 3))) {
+        bytes.push(parseInt(str.slice(i + 1, i + 3), 16));
+        i += 2;
+      } else {
+        encodeUTF8(str[i], (b) => {
+          bytes.push(...b);
+          return true;
+        });
+      }
+    }
+    return new UTF8Decoder(false).decode(bytes, false);
+  };
+
+  const parseQuery = (query) => {
+    const list = [];
+    for (const pair of query.split("&")) {
+      if (pair === "") {
+        continue;
+      }
+      const eq = pair.indexOf("=");
+      const name = eq < 0 ? pair : pair.slice(0, eq);
+      const value = eq < 0 ? "" : pair.slice(eq + 1);
+      list.push([percentDecode(name.replace(/\+/g, " ")), percentDecode(value.replace(/\+/g, " "))]);
+    }
+    return list;
+  };
+
+  // updateURL is the hook of the URL of search params, called when they change.
+  const updateURL = Symbol("updateURL");
+
+  class URLSearchParams {
+    #list = [];
+
+    constructor(init = "") {
+      if (typeof init === "object" && init !== null) {
+        if (typeof init[Symbol.iterator] === "function") {
+          for (const pair of init) {
+            const entry = [...pair];
+            if (entry.length !== 2) {
+              throw new TypeError("Each query pair must be an iterable [name, value] tuple");
+            }
+            this.#list.push([String(entry[0]), String(entry[1])]);
+          }
+        } else {
+          for (const name of Object.keys(init)) {
+            this.#list.push([name, String(init[name])]);
+          }
+        }
+      } else {
+        const query = String(init);
+        this.#list = parseQuery(query.startsWith("?") ? query.slice(1) : query);
+      }
+    }
+
+    // setQuery replaces the params by those of query, without updating the URL.
+    static setQuery(params, query) {
+      params.#list = parseQuery(query);
+    }
+
+    #update() {
+      if (this[updateURL]) {
+        this[updateURL](this.toString());
+      }
+    }
+
+    get size() {
+      return this.#list.length;
+    }
+
+    append(name, value) {
+      this.#list.push([String(name), String(value)]);
+      this.#update();
+    }
+
+    delete(name, value) {
+      name = String(name);
+      this.#list = this.#list.filter(([n, v]) => n !== name || (value !== undefined && v !== String(value)));
+      this.#update();
+    }
+
+    get(name) {
+      const entry = this.#list.find(([n]) => n === String(name));
+      return entry ? entry[1] : null;
+    }
+
+    getAll(name) {
+      return this.#list.filter(([n]) => n === String(name)).map(([, v]) => v);
+    }
+
+    has(name, value) {
+      return this.#list.some(([n, v]) => n === String(name) && (value === undefined || v === String(value)));
+    }
+
+    set(name, value) {
+      name = String(name);
+      const i = this.#list.findIndex(([n]) => n === name);
+      if (i < 0) {
+        this.#list.push([name, String(value)]);
+      } else {
+        this.#list[i][1] = String(value);
+        this.#list = this.#list.filter(([n], j) => n !== name || j <= i);
+      }
+      this.#update();
+    }
+
+    sort() {
+      // stable, by code units like the standard.
+      this.#list.sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+      this.#update();
+    }
+
+    forEach(callback, thisArg) {
+      for (const [name, value] of this.#list) {
+        callback.call(thisArg, value, name, this);
+      }
+    }
+
+    *entries() {
+      for (const [name, value] of this.#list) {
+        yield [name, value];
+      }
+    }
+
+    *keys() {
+      for (const [name] of this.#list) {
+        yield name;
+      }
+    }
+
+    *values() {
+      for (const [, value] of this.#list) {
+        yield value;
+      }
+    }
+
+    [Symbol.iterator]() {
+      return this.entries();
+    }
+
+    toString() {
+      return this.#list.map(([name, value]) => formEncode(name) + "=" + formEncode(value)).join("&");
+    }
+  }
+  const setQuery = URLSearchParams.setQuery;
+  delete URLSearchParams.setQuery;
+  define("URLSearchParams", URLSearchParams);
+
+  // URL
+
+  const specialSchemes = ["http:", "https:", "ws:", "wss:", "ftp:", "file:"];
+
+  // parse returns the components of the URL, or null if it is invalid.
+  const parse = (input, base) => {
+    const json = parseURL(String(input), base === undefined ? "" : String(base), base !== undefined);
+    return json === "" ? null : JSON.parse(json);
+  };
+
+  class URL {
+    #url;
+    #searchParams;
+
+    constructor(url, base) {
+      const parsed = parse(url, base);
+      if (parsed === null) {
+        throw new TypeError(`Invalid URL: ${url}`);
+      }
+      this.#url = parsed;
+      this.#searchParams = new URLSearchParams(parsed.search);
+      Object.defineProperty(this.#searchParams, updateURL, {
+        value: (query) => {
+          this.#url.search = query === "" ? "" : "?" + query;
+        },
+      });
+    }
+
+    static canParse(url, base) {
+      return parse(url, base) !== null;
+    }
+
+    static parse(url, base) {
+      return parse(url, base) === null ? null : new URL(url, base);
+    }
+
+    // set replaces a component of the URL, unless the URL would be invalid.
+    #set(component, value) {
+      const parsed = parse(this.#href({ ...this.#url, [component]: value }));
+      if (parsed !== null) {
+        this.#url = parsed;
+        setQuery(this.#searchParams, parsed.search.slice(1));
+      }
+    }
+
+    #href(url) {
+      let authority = "";
+      if (url.hostname !== "" || specialSchemes.includes(url.protocol)) {
+        let userinfo = url.username;
+        if (url.password !== "") {
+          userinfo += ":" + url.password;
+        }
+        authority = "//" + (userinfo !== "" ? userinfo + "@" : "") + url.hostname + (url.port !== "" ? ":" + url.port : "");
+      }
+      return url.protocol + authority + url.pathname + url.search + url.hash;
+    }
+
+    get href() {
+      return this.#href(this.#url);
+    }
+
+    set href(value) {
+      const parsed = parse(value);
+      if (parsed === null) {
+        throw new TypeError(`Invalid URL: ${value}`);
+      }
+      this.#url = parsed;
+      setQuery(this.#searchParams, parsed.search.slice(1));
+    }
+
+    get origin() {
+      const { protocol } = this.#url;
+      if (protocol === "file:" || !specialSchemes.includes(protocol)) {
+        return "null";
+      }
+      return protocol + "//" + this.host;
+    }
+
+    get protocol() {
+      return this.#url.protocol;
+    }
+
+    set protocol(value) {
+      const scheme = String(value).replace(/:.*$/, "");
+      if (/^[A-Za-z][A-Za-z0-9+\-.]*$/.test(scheme)) {
+        this.#set("protocol", scheme.toLowerCase() + ":");
+      }
+    }
+
+    get username() {
+      return this.#url.username;
+    }
+
+    set username(value) {
+      this.#set("username", encodeURIComponent(String(value)));
+    }
+
+    get password() {
+      return this.#url.password;
+    }
+
+    set password(value) {
+      this.#set("password", encodeURIComponent(String(value)));
+    }
+
+    get host() {
+      return this.#url.hostname + (this.#url.port !== "" ? ":" + this.#url.port : "");
+    }
+
+    set host(value) {
+      const [hostname, port] = String(value).split(":");
+      const parsed = parse(this.#href({ ...this.#url, hostname, port: port === undefined ? this.#url.port : port }));
+      if (parsed !== null) {
+        this.#url = parsed;
+      }
+    }
+
+    get hostname() {
+      return this.#url.hostname;
+    }
+
+    set hostname(value) {
+      this.#set("hostname", String(value));
+    }
+
+    get port() {
+      return this.#url.port;
+    }
+
+    set port(value) {
+      const port = String(value).match(/^\d*/)[0];
+      if (port === "" && String(value) !== "") {
+        return;
+      }
+      this.#set("port", port);
+    }
+
+    get pathname() {
+      return this.#url.pathname;
+    }
+
+    set pathname(value) {
+      let pathname = String(value);
+      if (this.#url.hostname !== "" && !pathname.startsWith("/")) {
+        pathname = "/" + pathname;
+      }
+      this.#set("pathname", encodeURI(pathname).replace(/[?#]/g, encodeURIComponent));
+    }
+
+    get search() {
+      return this.#url.search === "?" ? "" : this.#url.search;
+    }
+
+    set search(value) {
+      let search = String(value);
+      if (search.startsWith("?")) {
+        search = search.slice(1);
+      }
+      this.#set("search", search === "" ? "" : "?" + encodeURI(search).replace(/#/g, "%23"));
+    }
+
+    get searchParams() {
+      return this.#searchParams;
+    }
+
+    get hash() {
+      return this.#url.hash === "#" ? "" : this.#url.hash;
+    }
+
+    set hash(value) {
+      let hash = String(value);
+      if (hash.startsWith("#")) {
+        hash = hash.slice(1);
+      }
+      this.#set("hash", hash === "" ? "" : "#" + encodeURI(hash));
+    }
+
+    toString() {
+      return this.href;
+    }
+
+    toJSON() {
+      return this.href;
+    }
+  }
+  define("URL", URL);
+
+  // structuredClone
+
+  const typedArrays = [
+    Int8Array, Uint8Array, Uint8ClampedArray, Int16Array, Uint16Array,
+    Int32Array, Uint32Array, Float32Array, Float64Array, BigInt64Array, BigUint64Array,
+  ];
+  const errors = { Error, EvalError, RangeError, ReferenceError, SyntaxError, TypeError, URIError };
+
+  const dataCloneError = (value) =>
+    new domException(`${typeof value === "function" ? value.name || "function" : String(value)} could not be cloned.`, "DataCloneError");
+
+  const clone = (value, memory) => {
+    if (typeof value === "symbol" || typeof value === "function") {
+      throw dataCloneError(value);
+    }
+    if (typeof value !== "object" || value === null) {
+      return value;
+    }
+    if (memory.has(value)) {
+      return memory.get(value);
+    }
+    const tag = Object.prototype.toString.call(value).slice(8, -1);
+    const remember = (copy) => {
+      memory.set(value, copy);
+      return copy;
+    };
+    switch (tag) {
+      case "Boolean":
+      case "Number":
+      case "String":
+      case "BigInt":
+        return remember(Object(value.valueOf()));
+      case "Date":
+        return remember(new Date(value.getTime()));
+      case "RegExp":
+        return remember(new RegExp(value.source, value.flags));
+      case "ArrayBuffer":
+        return remember(value.slice(0));
+      case "DataView":
+        return remember(new DataView(clone(value.buffer, memory), value.byteOffset, value.byteLength));
+      case "Map": {
+        const copy = remember(new Map());
+        value.forEach((v, k) => copy.set(clone(k, memory), clone(v, memory)));
+        return copy;
+      }
+      case "Set": {
+        const copy = remember(new Set());
+        value.forEach((v) => copy.add(clone(v, memory)));
+        return copy;
+      }
+      case "Error": {
+        const ctor = Object.prototype.hasOwnProperty.call(errors, value.name) ? errors[value.name] : Error;
+        const copy = remember(new ctor(value.message));
+        if ("stack" in value) {
+          Object.defineProperty(copy, "stack", { value: String(value.stack), writable: true, configurable: true });
+        }
+        if ("cause" in value) {
+          Object.defineProperty(copy, "cause", { value: clone(value.cause, memory), writable: true, configurable: true });
+        }
+        return copy;
+      }
+      case "Array": {
+        const copy = remember(new Array(value.length));
+        for (const key of Object.keys(value)) {
+          copy[key] = clone(value[key], memory);
+        }
+        return copy;
+      }
+      case "Object": {
+        const copy = remember({});
+        for (const key of Object.keys(value)) {
+          copy[key] = clone(value[key], memory);
+        }
+        return copy;
+      }
+    }
+    const ctor = typedArrays.find((c) => value instanceof c);
+    if (ctor) {
+      return remember(new ctor(clone(value.buffer, memory), value.byteOffset, value.length));
+    }
+    throw dataCloneError(value);
+  };
+
+  define("structuredClone", function structuredClone(value, options = {}) {
+    if (arguments.length === 0) {
+      throw new TypeError("1 argument required, but only 0 present.");
+    }
+    if (options.transfer !== undefined && [...options.transfer].length > 0) {
+      throw new domException("Transferring is not supported.", "DataCloneError");
+    }
+    return clone(value, new Map());
+  });
+});
@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var ErrPoolClosed = fmt.Errorf("pool is closed")
+
+// defaultPoolHeadroomMB is the headroom of the near heap limit of the pooled isolates.
+const defaultPoolHeadroomMB = 16
+
+// PoolConfig configures an IsolatePool.
+type PoolConfig struct {
+	// Size is the maximum number of runners of the pool, idle or checked out.
+	Size int
+	// MinIdle is the number of idle runners the pool creates in advance, and
+	// replaces in the background once they are recycled.
+	MinIdle int
+	// MaxHeapSizeMB limits the heap of each isolate, 0 means the default limit of V8.
+	// A script that reaches it fails with ErrOutOfMemory, see NearHeapLimitOption.
+	MaxHeapSizeMB uint
+	// HeapHeadroomMB is the headroom of the near heap limit of each isolate, 0 means 16 MB.
+	HeapHeadroomMB uint
+	// Options are applied to each runner after MaxHeapSizeMB and HeapHeadroomMB.
+	Options []Option
+	// Bootstrap is run once by each runner before it is checked out for the first time,
+	// e.g. to define the functions used by the requests.
+	Bootstrap string
+	// MaxUses is the number of checkouts after which a runner is recycled, 0 means no limit.
+	MaxUses int
+	// MaxUsedHeapMB is the heap usage above which a returned runner is recycled, 0 means no limit.
+	// The usage is read when the runner is given back, without collecting the garbage first,
+	// so it includes the garbage of the last executions.
+	MaxUsedHeapMB uint
+}
+
+// IsolatePool is a pool of in-process runners, for trusted scripts that do not need to be
+// isolated in a separate process. Each runner has its own isolate, limited by
+// PoolConfig.MaxHeapSizeMB like MaxHeapSizeOption, i.e. through the create params of the
+// isolate, and a script that reaches the limit is terminated by its near-heap-limit callback
+// instead of aborting the process. A runner is checked out by Get, and must be given back by Put.
+// IsolatePool is safe to use concurrently, and must be closed after use.
+type IsolatePool struct {
+	fileName string
+	config   PoolConfig
+
+	// slots holds one token per runner of the pool, idle or checked out.
+	slots chan struct{}
+	// idle holds the runners ready to be checked out.
+	idle chan *Runner
+
+	// mu guards uses and closed.
+	mu     sync.Mutex
+	uses   map[*Runner]int
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewIsolatePool creates a pool of runners that run the given file, and creates
+// PoolConfig.MinIdle runners in advance.
+func NewIsolatePool(fileName string, config PoolConfig) (*IsolatePool, error) {
+	if config.Size <= 0 {
+		return nil, fmt.Errorf("invalid pool size: %d", config.Size)
+	}
+	if config.MinIdle > config.Size {
+		return nil, fmt.Errorf("min idle %d exceeds pool size %d", config.MinIdle, config.Size)
+	}
+	p := &IsolatePool{
+		fileName: strings.TrimSuffix(fileName, ".js") + ".js",
+		config:   config,
+		slots:    make(chan struct{}, config.Size),
+		idle:     make(chan *Runner, config.Size),
+		uses:     make(map[*Runner]int),
+	}
+	for i := 0; i < config.MinIdle; i++ {
+		p.slots <- struct{}{}
+		r, err := p.create(context.Background())
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.idle <- r
+	}
+	return p, nil
+}
+
+// create creates a runner for a slot already taken, and runs the bootstrap script.
+func (p *IsolatePool) create(ctx context.Context) (*Runner, error) {
+	options := []Option{MaxHeapSizeOption{HeapSizeMB: p.config.MaxHeapSizeMB}}
+	if p.config.MaxHeapSizeMB > 0 {
+		// a greedy script must not abort the process of the pool.
+		headroomMB := p.config.HeapHeadroomMB
+		if headroomMB == 0 {
+			headroomMB = defaultPoolHeadroomMB
+		}
+		options = append(options, NearHeapLimitOption{HeadroomMB: headroomMB})
+	}
+	options = append(options, p.config.Options...)
+	r, err := NewRunner(p.fileName, options...)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	if p.config.Bootstrap != "" {
+		if _, err := r.RunScript(ctx, p.config.Bootstrap); err != nil {
+			r.Close()
+			<-p.slots
+			return nil, fmt.Errorf("failed to bootstrap runner because: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Get checks out an idle runner, or creates one if the pool is not full.
+// It blocks until a runner is available, or ctx is done.
+func (p *IsolatePool) Get(ctx context.Context) (*Runner, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+	var r *Runner
+	select {
+	case r = <-p.idle: // prefer a warm runner
+	default:
+		select {
+		case r = <-p.idle:
+		case p.slots <- struct{}{}:
+			var err error
+			if r, err = p.create(ctx); err != nil {
+				// e.g. ctx is done while bootstrapping, the slot is free again
+				p.replenish()
+				return nil, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.discard(r)
+		return nil, ErrPoolClosed
+	}
+	p.uses[r]++
+	p.mu.Unlock()
+	return r, nil
+}
+
+func (p *IsolatePool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Put gives back a runner checked out by Get. The runner is recycled instead of being
+// reused if it has been closed, if one of its executions has been terminated (e.g. by a
+// timeout) because the state of its context may be inconsistent, if it has been reset by
+// NearHeapLimitOption because it lost the bootstrap, or if it exceeds
+// PoolConfig.MaxUses or PoolConfig.MaxUsedHeapMB.
+func (p *IsolatePool) Put(r *Runner) {
+	p.mu.Lock()
+	uses := p.uses[r]
+	p.mu.Unlock()
+	if p.shouldRecycle(r, uses) {
+		p.Discard(r)
+		return
+	}
+	p.mu.Lock()
+	if !p.closed {
+		// never blocks, there are no more runners than slots. It is done under mu
+		// so that Close cannot miss it.
+		p.idle <- r
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.discard(r)
+}
+
+// Discard closes a runner checked out by Get instead of giving it back, and lets the
+// pool create a new one in its place.
+func (p *IsolatePool) Discard(r *Runner) {
+	p.discard(r)
+	p.replenish()
+}
+
+func (p *IsolatePool) shouldRecycle(r *Runner, uses int) bool {
+	switch {
+	case r.closed || r.dirty:
+		return true
+	case p.config.MaxUses > 0 && uses >= p.config.MaxUses:
+		return true
+	case p.config.MaxUsedHeapMB > 0:
+		return r.vm.GetHeapStatistics().UsedHeapSize > uint64(p.config.MaxUsedHeapMB)<<20
+	}
+	return false
+}
+
+func (p *IsolatePool) discard(r *Runner) {
+	p.mu.Lock()
+	delete(p.uses, r)
+	p.mu.Unlock()
+	if !r.closed {
+		r.Close()
+	}
+	<-p.slots
+}
+
+// replenish creates a runner in the background if there are fewer than MinIdle idle runners.
+func (p *IsolatePool) replenish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.idle) >= p.config.MinIdle {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			return // the pool is full
+		}
+		r, err := p.create(context.Background())
+		if err != nil {
+			return
+		}
+		p.idle <- r // drained by Close, which waits for this goroutine
+	}()
+}
+
+// Close closes the idle runners. The runners checked out are closed once they are given back.
+func (p *IsolatePool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.wg.Wait()
+	for {
+		select {
+		case r := <-p.idle:
+			p.discard(r)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type IsolatePoolTestSuite struct {
+	suite.Suite
+}
+
+func TestIsolatePoolTestSuite(t *testing.T) {
+	suite.Run(t, new(IsolatePoolTestSuite))
+}
+
+func (suite *IsolatePoolTestSuite) TestCheckout() {
+	pool, err := NewIsolatePool("test.js", PoolConfig{
+		Size:      1,
+		MinIdle:   1,
+		Bootstrap: "const f = (x) => x * 2; var n = 0;",
+	})
+	suite.Require().NoError(err)
+	defer pool.Close()
+
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	val, err := r.RunScript(context.Background(), "n = f(21)")
+	suite.NoError(err)
+	suite.Equal("42", val.String())
+
+	// the pool is full until the runner is given back
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(ctx)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+
+	pool.Put(r)
+	r2, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.Same(r, r2)
+	val, err = r2.RunScript(context.Background(), "n")
+	suite.NoError(err)
+	suite.Equal("42", val.String())
+	pool.Put(r2)
+}
+
+func (suite *IsolatePoolTestSuite) TestRecycle() {
+	pool, err := NewIsolatePool("test.js", PoolConfig{
+		Size:      1,
+		Bootstrap: "var n = 0;",
+		MaxUses:   2,
+	})
+	suite.Require().NoError(err)
+	defer pool.Close()
+
+	count := func(r *Runner) string {
+		val, err := r.RunScript(context.Background(), "++n")
+		suite.Require().NoError(err)
+		return val.String()
+	}
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal("1", count(r))
+	pool.Put(r)
+	r, err = pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal("2", count(r))
+	pool.Put(r)
+	// recycled after MaxUses
+	r, err = pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal("1", count(r))
+
+	// recycled once an execution is terminated
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = r.RunScript(ctx, "while(true){}")
+	suite.ErrorIs(err, ErrorTimeout)
+	pool.Put(r)
+	r, err = pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal("1", count(r))
+	pool.Put(r)
+}
+
+func (suite *IsolatePoolTestSuite) TestHeapLimit() {
+	pool, err := NewIsolatePool("test.js", PoolConfig{Size: 1, MaxHeapSizeMB: 8})
+	suite.Require().NoError(err)
+	defer pool.Close()
+	other, err := NewRunner("test.js")
+	suite.Require().NoError(err)
+	defer other.Close()
+
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	defer pool.Put(r)
+	// the limit applies to the isolates of the pool only
+	suite.Equal(uint64(8), r.vm.GetHeapStatistics().HeapSizeLimit>>20)
+	suite.Greater(other.vm.GetHeapStatistics().HeapSizeLimit>>20, uint64(8))
+}
+
+func (suite *IsolatePoolTestSuite) TestClose() {
+	pool, err := NewIsolatePool("test.js", PoolConfig{Size: 2, MinIdle: 1})
+	suite.Require().NoError(err)
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	pool.Close()
+	_, err = pool.Get(context.Background())
+	suite.ErrorIs(err, ErrPoolClosed)
+	// a runner given back after Close is closed
+	pool.Put(r)
+	suite.True(r.closed)
+}
+
+func (suite *IsolatePoolTestSuite) TestNearHeapLimit() {
+	pool, err := NewIsolatePool("test.js", PoolConfig{
+		Size:          1,
+		MaxHeapSizeMB: 16,
+		Options:       []Option{NearHeapLimitOption{HeadroomMB: 32}},
+		Bootstrap:     "var n = 1;",
+	})
+	suite.Require().NoError(err)
+	defer pool.Close()
+
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	_, err = r.RunScript(context.Background(), `
+		var hog = [];
+		for (let i = 0; i < 30; i++) { hog.push(new Array(1024 * 1024 / 4).fill(i)); } // 30MB
+	`)
+	suite.ErrorIs(err, ErrOutOfMemory)
+	// the runner is reset, and still usable
+	val, err := r.RunScript(context.Background(), "typeof hog")
+	suite.NoError(err)
+	suite.Equal("undefined", val.String())
+	// but it lost the bootstrap, so it is recycled
+	pool.Put(r)
+	r, err = pool.Get(context.Background())
+	suite.Require().NoError(err)
+	val, err = r.RunScript(context.Background(), "n")
+	suite.NoError(err)
+	suite.Equal("1", val.String())
+	pool.Put(r)
+}
+
+func (suite *IsolatePoolTestSuite) TestGreedyScript() {
+	pool, err := NewIsolatePool("test.js", PoolConfig{Size: 2, MinIdle: 2, MaxHeapSizeMB: 16})
+	suite.Require().NoError(err)
+	defer pool.Close()
+	greedy, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	other, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	defer pool.Put(other)
+
+	// V8 would abort the process of the test if the script was not terminated
+	_, err = greedy.RunScript(context.Background(), `
+		const hog = [];
+		while (true) { hog.push(new Array(1024 * 64).fill(1)); }
+	`)
+	suite.ErrorIs(err, ErrOutOfMemory)
+	pool.Put(greedy)
+	val, err := other.RunScript(context.Background(), "1+1")
+	suite.NoError(err)
+	suite.Equal("2", val.String())
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	val, err = r.RunScript(context.Background(), "1+1")
+	suite.NoError(err)
+	suite.Equal("2", val.String())
+	pool.Put(r)
+}
+
+// failOption fails to create the next runners, as many as fails.
+type failOption struct {
+	fails *atomic.Int32
+}
+
+func (o failOption) apply(c *config) error {
+	if o.fails.Add(-1) >= 0 {
+		return fmt.Errorf("failed to create runner")
+	}
+	return nil
+}
+
+func (suite *IsolatePoolTestSuite) TestReplenishAfterCreateFailure() {
+	fails := &atomic.Int32{}
+	pool, err := NewIsolatePool("test.js", PoolConfig{
+		Size:    2,
+		MinIdle: 1,
+		Options: []Option{failOption{fails: fails}},
+	})
+	suite.Require().NoError(err)
+	defer pool.Close()
+
+	r, err := pool.Get(context.Background())
+	suite.Require().NoError(err)
+	defer pool.Put(r)
+	fails.Store(1)
+	_, err = pool.Get(context.Background())
+	suite.ErrorContains(err, "failed to create runner")
+
+	// the failed creation freed its slot, and an idle runner is created in its place
+	suite.Eventually(func() bool {
+		return len(pool.idle) == 1
+	}, time.Second, 10*time.Millisecond)
+}
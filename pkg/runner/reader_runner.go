@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
 )
 
 type ReaderRunner struct {
@@ -15,6 +18,35 @@ type ReaderRunner struct {
 	MaxHeapSizeMB uint
 	Input         io.Reader
 	Output        io.Writer
+
+	// HeapHeadroomMB lets the heap grow beyond MaxHeapSizeMB before V8 aborts the process,
+	// so that a request exceeding MaxHeapSizeMB is terminated with an out of memory error
+	// instead, see NearHeapLimitOption. 0 means the process aborts at MaxHeapSizeMB.
+	HeapHeadroomMB uint
+	// Snapshot boots the isolate, see SnapshotOption.
+	Snapshot *Snapshot
+	// ScriptCache caches the scripts compiled by the requests, see ScriptCacheOption.
+	// Each response reports how its scripts were compiled.
+	ScriptCache *ScriptCacheOption
+	// Polyfills defines the web platform APIs missing from V8, see PolyfillsOption.
+	Polyfills bool
+	// Deterministic replaces the non-deterministic APIs, which are seeded by the Determinism
+	// of each request, or the zero Determinism, see DeterministicOption.
+	Deterministic bool
+	// Lockdown restricts the language surface of the requests, see LockdownOption.
+	Lockdown LockdownOption
+	// TraceLog receives a line with the TraceID of each request before it runs, if set,
+	// see types.TraceLogPrefix.
+	TraceLog io.Writer
+
+	// MaxCodeSize bounds the length of the code of a request, 0 means no limit.
+	MaxCodeSize int
+	// MaxResultSize bounds the JSON result of a request, 0 means no limit.
+	// It applies to the sum of the items of a map request, and to each value of a stream.
+	MaxResultSize int
+	// MaxLogSize bounds the length of an error message, longer messages are truncated.
+	// 0 means no limit.
+	MaxLogSize int
 }
 
 // NewReaderRunner creates a new ReaderRunner that reads from input and writes to output.
@@ -39,7 +71,24 @@ func NewStdioRunner(fileName string, maxHeapSizeMB uint) (*ReaderRunner, error)
 
 func (r *ReaderRunner) Process() error {
 	ctx := context.Background()
-	runner, err := NewRunner(r.FileName, MaxHeapSizeOption{HeapSizeMB: r.MaxHeapSizeMB})
+	options := []Option{MaxHeapSizeOption{HeapSizeMB: r.MaxHeapSizeMB}}
+	if r.HeapHeadroomMB > 0 && r.MaxHeapSizeMB > 0 {
+		options = append(options, NearHeapLimitOption{HeadroomMB: r.HeapHeadroomMB})
+	}
+	if r.Snapshot != nil {
+		options = append(options, SnapshotOption{Snapshot: r.Snapshot})
+	}
+	if r.ScriptCache != nil {
+		options = append(options, *r.ScriptCache)
+	}
+	if r.Polyfills {
+		options = append(options, PolyfillsOption{})
+	}
+	if r.Deterministic {
+		options = append(options, DeterministicOption{})
+	}
+	options = append(options, r.Lockdown)
+	runner, err := NewRunner(r.FileName, options...)
 	if err != nil {
 		return fmt.Errorf("failed to create runner: %v", err)
 	}
@@ -47,6 +96,10 @@ func (r *ReaderRunner) Process() error {
 
 	in := gob.NewDecoder(r.Input)
 	out := gob.NewEncoder(r.Output)
+	send := func(res types.RunCodeResponse) error {
+		r.truncateErrors(&res)
+		return out.Encode(res)
+	}
 
 	for {
 		var req types.RunCodeRequest
@@ -60,31 +113,214 @@ func (r *ReaderRunner) Process() error {
 			return fmt.Errorf("failed to decode req: %w", err)
 		}
 
-		val, err := runner.RunScript(ctx, req.Code)
-		if err != nil {
-			if err = out.Encode(errResult(req.ID, err)); err != nil {
-				return err
-			}
-			continue
+		if err = r.handle(ctx, runner, req, send); err != nil {
+			return err
 		}
+	}
+}
 
-		switch req.ResponseType {
-		case types.RtnValueTypeNil:
-			if err = out.Encode(nilResult(req.ID)); err != nil {
-				return err
-			}
-			continue
-		case types.RtnValueTypeJSON:
-			if err = out.Encode(jsonResult(req.ID, runner.CodeCtx(), val)); err != nil {
-				return err
+// handle runs req and sends its response(s).
+func (r *ReaderRunner) handle(
+	ctx context.Context,
+	runner *Runner,
+	req types.RunCodeRequest,
+	send func(types.RunCodeResponse) error,
+) error {
+	if r.TraceLog != nil {
+		// a request without TraceID ends the trace of the previous one.
+		if _, err := fmt.Fprintf(r.TraceLog, "%s%s\n", types.TraceLogPrefix, req.TraceID); err != nil {
+			return fmt.Errorf("failed to write trace log: %w", err)
+		}
+	}
+	if req.Stats {
+		send = withExecStats(runner, send)
+	}
+	if r.MaxCodeSize > 0 && req.CodeSize() > r.MaxCodeSize {
+		return send(errResult(req.ID,
+			fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrCodeTooLarge, req.CodeSize(), r.MaxCodeSize)))
+	}
+	if !req.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+		if ctx.Err() != nil {
+			return send(errResult(req.ID, fmt.Errorf("%w: deadline exceeded before execution", ErrorTimeout)))
+		}
+	}
+	if req.CPUBudget > 0 {
+		ctx = WithCPUBudget(ctx, req.CPUBudget)
+	}
+	if r.ScriptCache != nil {
+		send = withScriptCacheStats(runner, send)
+	}
+	if r.Deterministic || req.Determinism != nil {
+		var d types.Determinism
+		if req.Determinism != nil {
+			d = *req.Determinism
+		}
+		if err := runner.Seed(d); err != nil {
+			return send(errResult(req.ID, err))
+		}
+	}
+	switch req.Type {
+	case "", types.ReqTypeRun:
+		if req.ResponseType == types.RtnValueTypeStream {
+			return r.streamResult(ctx, runner, req, send)
+		}
+		return send(r.runResult(ctx, runner, req))
+	case types.ReqTypeMap:
+		return send(r.mapResult(ctx, runner, req))
+	case types.ReqTypeCompile:
+		if err := runner.Compile(ctx, req.Name, req.Code); err != nil {
+			return send(errResult(req.ID, err))
+		}
+		return send(nilResult(req.ID))
+	case types.ReqTypeRunCompiled:
+		val, err := runner.RunCompiled(ctx, req.Name)
+		return send(r.valueResult(runner, req, val, err))
+	case types.ReqTypeModules:
+		runner.RegisterModules(req.Modules)
+		return send(nilResult(req.ID))
+	case types.ReqTypeCallModule:
+		return send(r.callModuleResult(ctx, runner, req))
+	case types.ReqTypeFiles:
+		if err := runner.SetFiles(req.Files); err != nil {
+			return send(errResult(req.ID, err))
+		}
+		return send(nilResult(req.ID))
+	case types.ReqTypePing:
+		return send(nilResult(req.ID))
+	default:
+		return send(errResult(req.ID, fmt.Errorf("unknown request type: %s", req.Type)))
+	}
+}
+
+// withScriptCacheStats sets the script cache stats of the request on its last response.
+func withScriptCacheStats(
+	runner *Runner,
+	send func(types.RunCodeResponse) error,
+) func(types.RunCodeResponse) error {
+	before := runner.ScriptCacheStats()
+	return func(res types.RunCodeResponse) error {
+		if !res.More {
+			stats := runner.ScriptCacheStats().Sub(before)
+			res.ScriptCache = &stats
+		}
+		return send(res)
+	}
+}
+
+// withExecStats sets the execution stats of the request on its last response.
+func withExecStats(
+	runner *Runner,
+	send func(types.RunCodeResponse) error,
+) func(types.RunCodeResponse) error {
+	start := time.Now()
+	before := runner.Usage()
+	heapBefore := runner.heapStatistics()
+	return func(res types.RunCodeResponse) error {
+		if !res.More {
+			usage := runner.Usage()
+			heap := runner.heapStatistics()
+			res.Stats = &types.ExecStats{
+				WallTime:       time.Since(start),
+				CPUTime:        usage.CPUTime - before.CPUTime,
+				CompileTime:    usage.CompileTime - before.CompileTime,
+				GCCount:        usage.GCCount - before.GCCount,
+				HeapUsedBefore: heapBefore.UsedHeapSize,
+				HeapUsedAfter:  heap.UsedHeapSize,
+				TotalHeapSize:  heap.TotalHeapSize,
+				ExternalMemory: heap.ExternalMemory,
 			}
-			continue
-		default:
-			if err = out.Encode(
-				errResult(req.ID,
-					fmt.Errorf("unknown response type: %s", req.ResponseType))); err != nil {
-				return err
+		}
+		return send(res)
+	}
+}
+
+func (r *ReaderRunner) runResult(
+	ctx context.Context,
+	runner *Runner,
+	req types.RunCodeRequest,
+) types.RunCodeResponse {
+	val, err := runner.RunScript(ctx, req.Code)
+	return r.valueResult(runner, req, val, err)
+}
+
+// callModuleResult calls the function req.Code exported by the module req.Name with req.Inputs.
+func (r *ReaderRunner) callModuleResult(
+	ctx context.Context,
+	runner *Runner,
+	req types.RunCodeRequest,
+) types.RunCodeResponse {
+	name := req.Code
+	if name == "" {
+		name = "default"
+	}
+	args := make([]v8.Valuer, len(req.Inputs))
+	for i, input := range req.Inputs {
+		arg, err := v8.JSONParse(runner.CodeCtx(), input)
+		if err != nil {
+			return errResult(req.ID, fmt.Errorf("failed to parse input because: %w", err))
+		}
+		args[i] = arg
+	}
+	val, err := runner.CallModule(ctx, req.Name, name, args...)
+	return r.valueResult(runner, req, val, err)
+}
+
+// valueResult converts the outcome of running the script of req to its response.
+func (r *ReaderRunner) valueResult(
+	runner *Runner,
+	req types.RunCodeRequest,
+	val *v8.Value,
+	err error,
+) types.RunCodeResponse {
+	if err != nil {
+		return errResult(req.ID, err)
+	}
+
+	switch req.ResponseType {
+	case types.RtnValueTypeNil:
+		return nilResult(req.ID)
+	case types.RtnValueTypeJSON:
+		res := jsonResult(req.ID, runner.CodeCtx(), val)
+		if res.Result != nil {
+			if err := r.checkResultSize(len(*res.Result)); err != nil {
+				return errResult(req.ID, err)
 			}
 		}
+		return res
+	default:
+		return errResult(req.ID, fmt.Errorf("unknown response type: %s", req.ResponseType))
+	}
+}
+
+func (r *ReaderRunner) checkResultSize(size int) error {
+	if r.MaxResultSize > 0 && size > r.MaxResultSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrResultTooLarge, size, r.MaxResultSize)
+	}
+	return nil
+}
+
+// truncateErrors truncates the error messages of res to MaxLogSize.
+func (r *ReaderRunner) truncateErrors(res *types.RunCodeResponse) {
+	if r.MaxLogSize <= 0 {
+		return
+	}
+	res.Error = truncate(res.Error, r.MaxLogSize)
+	for i := range res.Items {
+		res.Items[i].Error = truncate(res.Items[i].Error, r.MaxLogSize)
+	}
+}
+
+func truncate(s *string, n int) *string {
+	if s == nil || len(*s) <= n {
+		return s
+	}
+	// do not split a multi-byte character
+	for n > 0 && !utf8.RuneStart((*s)[n]) {
+		n--
 	}
+	t := (*s)[:n]
+	return &t
 }
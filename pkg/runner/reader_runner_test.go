@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"github.com/stumble/v8runner/pkg/types"
@@ -212,6 +213,331 @@ func (suite *ReaderRunnerTestSuite) TestPipeline() {
 	wg.Wait()
 }
 
+func (suite *ReaderRunnerTestSuite) TestMap() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	suite.NoError(writeToBuf.Encode(types.RunCodeRequest{
+		ID:           "x",
+		Code:         "function f(v) { if (v.loop) { while(true){} } return v.a * 2; }",
+		ResponseType: types.RtnValueTypeNil,
+	}))
+	suite.NoError(writeToBuf.Encode(types.RunCodeRequest{
+		ID:           "y",
+		Type:         types.ReqTypeMap,
+		Code:         "f",
+		ResponseType: types.RtnValueTypeJSON,
+		Inputs:       []string{`{"a":1}`, `{"loop":true}`, `{`, `{"a":3}`},
+		ItemTimeout:  100 * time.Millisecond,
+	}))
+	suite.NoError(writeToBuf.Encode(types.RunCodeRequest{
+		ID:           "z",
+		Type:         types.ReqTypeMap,
+		Code:         "1",
+		ResponseType: types.RtnValueTypeJSON,
+		Inputs:       []string{`1`},
+	}))
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	err = runner.Process()
+	suite.Require().NoError(err)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	res := types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal("y", res.ID)
+	suite.Nil(res.Error)
+	suite.Require().Len(res.Items, 4)
+	suite.Equal(types.ItemResult{Result: ptr("2")}, res.Items[0])
+	suite.Contains(*res.Items[1].Error, "timeout")
+	suite.Contains(*res.Items[2].Error, "failed to parse input")
+	// the runner is still usable after an item timeout
+	suite.Equal(types.ItemResult{Result: ptr("6")}, res.Items[3])
+
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal("z", res.ID)
+	suite.Require().NotNil(res.Error)
+	suite.Contains(*res.Error, "failed to map")
+}
+
+func (suite *ReaderRunnerTestSuite) TestStream() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	suite.NoError(writeToBuf.Encode(types.RunCodeRequest{
+		ID:           "x",
+		Code:         "(function*() { for (let i = 0; i < 3; i++) { yield {i}; } })()",
+		ResponseType: types.RtnValueTypeStream,
+	}))
+	suite.NoError(writeToBuf.Encode(types.RunCodeRequest{
+		ID:            "y",
+		Code:          "['aaaa', 'bbbb', 'cccc']",
+		ResponseType:  types.RtnValueTypeStream,
+		MaxStreamSize: 13,
+	}))
+	suite.NoError(writeToBuf.Encode(types.RunCodeRequest{
+		ID:           "z",
+		Code:         "1",
+		ResponseType: types.RtnValueTypeStream,
+	}))
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	err = runner.Process()
+	suite.Require().NoError(err)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	for _, expected := range []types.RunCodeResponse{
+		{ID: "x", Result: ptr(`{"i":0}`), More: true},
+		{ID: "x", Result: ptr(`{"i":1}`), More: true},
+		{ID: "x", Result: ptr(`{"i":2}`), More: true},
+		{ID: "x"},
+		{ID: "y", Result: ptr(`"aaaa"`), More: true},
+		{ID: "y", Result: ptr(`"bbbb"`), More: true},
+		{
+			ID:        "y",
+			Error:     ptr("stream too large: exceeds 13 bytes"),
+			ErrorCode: types.ErrCodeStreamTooLarge,
+		},
+	} {
+		res := types.RunCodeResponse{}
+		suite.NoError(readFromBuf.Decode(&res))
+		suite.Equal(expected, res)
+	}
+	res := types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal("z", res.ID)
+	suite.Require().NotNil(res.Error)
+	suite.Contains(*res.Error, "result is not iterable")
+}
+
+func (suite *ReaderRunnerTestSuite) TestLimits() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	for _, req := range []types.RunCodeRequest{
+		{ID: "code", Code: strings.Repeat(" ", 300) + "1", ResponseType: types.RtnValueTypeJSON},
+		{ID: "result", Code: "'x'.repeat(100)", ResponseType: types.RtnValueTypeJSON},
+		{ID: "log", Code: "throw '" + strings.Repeat("é", 100) + "'", ResponseType: types.RtnValueTypeJSON},
+		{ID: "ok", Code: "'x'.repeat(10)", ResponseType: types.RtnValueTypeJSON},
+	} {
+		suite.NoError(writeToBuf.Encode(req))
+	}
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	runner.MaxCodeSize = 250
+	runner.MaxResultSize = 50
+	runner.MaxLogSize = 51
+	err = runner.Process()
+	suite.Require().NoError(err)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	for _, expected := range []types.RunCodeResponse{
+		{
+			ID:        "code",
+			Error:     ptr("code too large: 301 bytes exceeds 250 bytes"),
+			ErrorCode: types.ErrCodeCodeTooLarge,
+		},
+		{
+			ID:        "result",
+			Error:     ptr("result too large: 102 bytes exceeds 50 bytes"),
+			ErrorCode: types.ErrCodeResultTooLarge,
+		},
+		{
+			ID: "log",
+			// truncated to 51 bytes, without splitting a character
+			Error: ptr("failed to run script because: éééééééééé"),
+		},
+		{ID: "ok", Result: ptr(`"xxxxxxxxxx"`)},
+	} {
+		res := types.RunCodeResponse{}
+		suite.NoError(readFromBuf.Decode(&res))
+		suite.Equal(expected, res)
+	}
+}
+
+func (suite *ReaderRunnerTestSuite) TestCPUBudget() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	for _, req := range []types.RunCodeRequest{
+		{ID: "x", Code: "let n = 0; while(true){ n++; }", ResponseType: types.RtnValueTypeJSON, CPUBudget: 50 * time.Millisecond},
+		{ID: "y", Code: "n > 0", ResponseType: types.RtnValueTypeJSON, CPUBudget: 50 * time.Millisecond},
+	} {
+		suite.NoError(writeToBuf.Encode(req))
+	}
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	start := time.Now()
+	err = runner.Process()
+	suite.Require().NoError(err)
+	suite.Less(time.Since(start), time.Second)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	res := types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{
+		ID:        "x",
+		Error:     ptr("cpu budget exceeded: 50ms"),
+		ErrorCode: types.ErrCodeCPUBudgetExceeded,
+	}, res)
+	// the runner is still usable, and keeps the state of the terminated script
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "y", Result: ptr("true")}, res)
+}
+
+func (suite *ReaderRunnerTestSuite) TestDeadline() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	for _, req := range []types.RunCodeRequest{
+		{ID: "x", Code: "let n = 0; while(true){ n++; }", ResponseType: types.RtnValueTypeJSON, Deadline: time.Now().Add(100 * time.Millisecond)},
+		{ID: "y", Code: "n = -1", ResponseType: types.RtnValueTypeJSON, Deadline: time.Now().Add(-time.Second)},
+		{ID: "z", Code: "n > 0", ResponseType: types.RtnValueTypeJSON},
+	} {
+		suite.NoError(writeToBuf.Encode(req))
+	}
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	err = runner.Process()
+	suite.Require().NoError(err)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	res := types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal("x", res.ID)
+	suite.Require().NotNil(res.Error)
+	suite.True(strings.HasPrefix(*res.Error, "timeout: "), *res.Error)
+	suite.Equal(types.ErrCodeTimeout, res.ErrorCode)
+	// a request received after its deadline is not executed
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{
+		ID:        "y",
+		Error:     ptr("timeout: deadline exceeded before execution"),
+		ErrorCode: types.ErrCodeTimeout,
+	}, res)
+	// the runner is still usable, and keeps the state of the terminated script
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "z", Result: ptr("true")}, res)
+}
+
+func (suite *ReaderRunnerTestSuite) TestOutOfMemory() {
+	hog := "var hog = []; while (true) { hog.push(new Array(1024 * 1024).fill(0)); }"
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	for _, req := range []types.RunCodeRequest{
+		{ID: "x", Code: "var n = 1; const f = (x) => { if (x) { " + hog + " } return x; }", ResponseType: types.RtnValueTypeNil},
+		{ID: "y", Code: hog, ResponseType: types.RtnValueTypeJSON},
+		{ID: "z", Code: "typeof n", ResponseType: types.RtnValueTypeJSON},
+		{ID: "w", Code: "var n = 1; const f = (x) => { if (x) { " + hog + " } return x; }", ResponseType: types.RtnValueTypeNil},
+		{ID: "v", Type: types.ReqTypeMap, Code: "f", Inputs: []string{"0", "1", "0"}, ResponseType: types.RtnValueTypeJSON},
+	} {
+		suite.NoError(writeToBuf.Encode(req))
+	}
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 8)
+	suite.NoError(err)
+	runner.HeapHeadroomMB = 32
+	err = runner.Process()
+	suite.Require().NoError(err)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	res := types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "x"}, res)
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal("y", res.ID)
+	suite.Equal(types.ErrCodeOutOfMemory, res.ErrorCode)
+	// the runner is reset
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "z", Result: ptr(`"undefined"`)}, res)
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "w"}, res)
+	// the items after the one out of memory are not started
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal("v", res.ID)
+	suite.Require().Len(res.Items, 3)
+	suite.Equal(types.ItemResult{Result: ptr("0")}, res.Items[0])
+	suite.Equal(types.ErrCodeOutOfMemory, res.Items[1].ErrorCode)
+	suite.Equal(types.ItemResult{
+		Error:     ptr("out of memory: item not started"),
+		ErrorCode: types.ErrCodeOutOfMemory,
+	}, res.Items[2])
+}
+
 func ptr[T any](s T) *T {
 	return &s
 }
+
+func (suite *ReaderRunnerTestSuite) TestStats() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	for _, req := range []types.RunCodeRequest{
+		{ID: "x", Code: "var kept = new Array(100000).fill(1); kept.length", ResponseType: types.RtnValueTypeJSON, Stats: true},
+		{ID: "y", Code: "kept.length", ResponseType: types.RtnValueTypeJSON},
+	} {
+		suite.NoError(writeToBuf.Encode(req))
+	}
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	err = runner.Process()
+	suite.Require().NoError(err)
+	readFromBuf := gob.NewDecoder(strings.NewReader(result.String()))
+	res := types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(ptr("100000"), res.Result)
+	suite.Require().NotNil(res.Stats)
+	suite.Greater(res.Stats.WallTime, time.Duration(0))
+	suite.Greater(res.Stats.CPUTime, time.Duration(0))
+	suite.Greater(res.Stats.CompileTime, time.Duration(0))
+	suite.Greater(res.Stats.HeapUsedAfter, res.Stats.HeapUsedBefore)
+	suite.GreaterOrEqual(res.Stats.TotalHeapSize, res.Stats.HeapUsedAfter)
+	// the stats are only sent when requested
+	res = types.RunCodeResponse{}
+	suite.NoError(readFromBuf.Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "y", Result: ptr("100000")}, res)
+}
+
+func (suite *ReaderRunnerTestSuite) TestTraceLog() {
+	buf := &bytes.Buffer{}
+	writeToBuf := gob.NewEncoder(buf)
+	for _, req := range []types.RunCodeRequest{
+		{ID: "x", Code: "1", ResponseType: types.RtnValueTypeJSON, TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{ID: "y", Code: "2", ResponseType: types.RtnValueTypeJSON},
+	} {
+		suite.NoError(writeToBuf.Encode(req))
+	}
+
+	result := &strings.Builder{}
+	traceLog := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	runner.TraceLog = traceLog
+	suite.Require().NoError(runner.Process())
+	suite.Equal("v8runner trace_id=4bf92f3577b34da6a3ce929d0e0e4736\nv8runner trace_id=\n", traceLog.String())
+}
+
+func (suite *ReaderRunnerTestSuite) TestPing() {
+	buf := &bytes.Buffer{}
+	suite.NoError(gob.NewEncoder(buf).Encode(types.RunCodeRequest{ID: "x", Type: types.ReqTypePing}))
+
+	result := &strings.Builder{}
+	runner, err := NewReaderRunner(buf, result, "test.js", 16)
+	suite.NoError(err)
+	suite.Require().NoError(runner.Process())
+	res := types.RunCodeResponse{}
+	suite.NoError(gob.NewDecoder(strings.NewReader(result.String())).Decode(&res))
+	suite.Equal(types.RunCodeResponse{ID: "x"}, res)
+}
@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// files is the read-only virtual filesystem of the CommonJS modules of a runner,
+// see SetFiles. The scripts of the runner have no access to the real disk.
+type files struct {
+	// sources are the contents of the files, by absolute path.
+	sources map[string]string
+	// cache are the module objects of the files evaluated in the context of the runner,
+	// by absolute path, like require.cache of Node.
+	cache map[string]*v8.Object
+	// makeRequire creates the require function of a directory, created with the context.
+	makeRequire *v8.Function
+}
+
+// requireFactory returns the function creating the require function of a directory,
+// given the function loading a module from a directory.
+const requireFactory = `(function (load) {
+	return function (dir) {
+		return function require(id) {
+			if (typeof id !== "string") {
+				throw new TypeError("The \"id\" argument must be of type string");
+			}
+			return load(dir, id);
+		};
+	};
+})`
+
+// SetFiles replaces the virtual filesystem of the runner by files, by path, and defines
+// a global require function loading the CommonJS modules of files. Paths are relative to
+// the root of the filesystem, which is the directory of the scripts of the runner.
+// Modules are resolved like Node: relative ids against the directory of the requiring
+// module, trying the .js and .json extensions and the directories with a package.json
+// main or an index.js, and bare ids in the node_modules directories of its ancestors.
+// A module is evaluated once, the first time it is required, and the modules evaluated
+// before SetFiles are discarded.
+func (r *Runner) SetFiles(sources map[string]string) error {
+	if r.closed {
+		return fmt.Errorf("runner is closed")
+	}
+	r.files = &files{sources: make(map[string]string, len(sources))}
+	for name, source := range sources {
+		r.files.sources[path.Join("/", name)] = source
+	}
+	return r.defineRequire()
+}
+
+// defineRequire defines the global require function of the files of the runner, if any.
+func (r *Runner) defineRequire() error {
+	if r.files == nil {
+		return nil
+	}
+	r.files.cache = nil
+	require, err := r.requireFn("/")
+	if err != nil {
+		return fmt.Errorf("failed to define require because: %w", err)
+	}
+	return r.codeCtx.Global().Set("require", require)
+}
+
+// requireFn returns the require function resolving ids against dir.
+func (r *Runner) requireFn(dir string) (*v8.Value, error) {
+	if r.files.makeRequire == nil {
+		load := v8.NewFunctionTemplateWithError(r.vm, func(info *v8.FunctionCallbackInfo) (*v8.Value, error) {
+			args := info.Args()
+			return r.require(args[0].String(), args[1].String())
+		})
+		factory, err := r.codeCtx.RunScript(requireFactory, "require.js")
+		if err != nil {
+			return nil, err
+		}
+		fn, err := factory.AsFunction()
+		if err != nil {
+			return nil, err
+		}
+		val, err := fn.Call(v8.Undefined(r.vm), load.GetFunction(r.codeCtx))
+		if err != nil {
+			return nil, err
+		}
+		if r.files.makeRequire, err = val.AsFunction(); err != nil {
+			return nil, err
+		}
+	}
+	dirVal, err := v8.NewValue(r.vm, dir)
+	if err != nil {
+		return nil, err
+	}
+	return r.files.makeRequire.Call(v8.Undefined(r.vm), dirVal)
+}
+
+// require returns the exports of the module id required from dir, evaluating it if it
+// is required for the first time. The module is cached before it is evaluated, so that
+// modules requiring each other see the exports defined so far, like Node.
+func (r *Runner) require(dir, id string) (*v8.Value, error) {
+	name, ok := r.files.resolve(dir, id)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot find module '%s' from '%s'", ErrModuleNotFound, id, dir)
+	}
+	if module, ok := r.files.cache[name]; ok {
+		return module.Get("exports")
+	}
+
+	module, err := v8.NewObjectTemplate(r.vm).NewInstance(r.codeCtx)
+	if err != nil {
+		return nil, err
+	}
+	exports, err := v8.NewObjectTemplate(r.vm).NewInstance(r.codeCtx)
+	if err != nil {
+		return nil, err
+	}
+	if err := module.Set("id", name); err != nil {
+		return nil, err
+	}
+	if err := module.Set("exports", exports); err != nil {
+		return nil, err
+	}
+	if r.files.cache == nil {
+		r.files.cache = make(map[string]*v8.Object)
+	}
+	r.files.cache[name] = module
+
+	if err := r.evalFile(name, module); err != nil {
+		delete(r.files.cache, name)
+		return nil, err
+	}
+	return module.Get("exports")
+}
+
+// evalFile evaluates the file name as the given module.
+func (r *Runner) evalFile(name string, module *v8.Object) error {
+	source := r.files.sources[name]
+	if path.Ext(name) == ".json" {
+		val, err := v8.JSONParse(r.codeCtx, source)
+		if err != nil {
+			return fmt.Errorf("failed to require %s because: %w", name, err)
+		}
+		return module.Set("exports", val)
+	}
+
+	// the wrapper is on the first line, so that the line numbers of errors are the
+	// line numbers of the file.
+	wrapped := "(function (exports, require, module, __filename, __dirname) {" + source + "\n})"
+	start := time.Now()
+	val, err := r.codeCtx.RunScript(wrapped, strings.TrimPrefix(name, "/")) // only creates the module function
+	r.timeCompile(start)
+	if err != nil {
+		return fmt.Errorf("failed to require %s because: %w", name, err)
+	}
+	fn, err := val.AsFunction()
+	if err != nil {
+		return fmt.Errorf("failed to require %s because: %w", name, err)
+	}
+	exports, err := module.Get("exports")
+	if err != nil {
+		return err
+	}
+	require, err := r.requireFn(path.Dir(name))
+	if err != nil {
+		return err
+	}
+	filename, err := v8.NewValue(r.vm, name)
+	if err != nil {
+		return err
+	}
+	dirname, err := v8.NewValue(r.vm, path.Dir(name))
+	if err != nil {
+		return err
+	}
+	if _, err := fn.Call(exports, exports, require, module, filename, dirname); err != nil {
+		return fmt.Errorf("failed to require %s because: %w", name, err)
+	}
+	return nil
+}
+
+// resolve returns the path of the file designated by id when required from dir.
+func (f *files) resolve(dir, id string) (string, bool) {
+	if strings.HasPrefix(id, "./") || strings.HasPrefix(id, "../") || strings.HasPrefix(id, "/") ||
+		id == "." || id == ".." {
+		return f.resolvePath(path.Join(dir, id))
+	}
+	// bare ids are looked up in the node_modules of dir and its ancestors.
+	for d := dir; ; d = path.Dir(d) {
+		if path.Base(d) != "node_modules" {
+			if name, ok := f.resolvePath(path.Join(d, "node_modules", id)); ok {
+				return name, true
+			}
+		}
+		if d == "/" {
+			return "", false
+		}
+	}
+}
+
+// resolvePath resolves p as a file, and then as a directory.
+func (f *files) resolvePath(p string) (string, bool) {
+	for _, candidate := range []string{p, p + ".js", p + ".json"} {
+		if _, ok := f.sources[candidate]; ok {
+			return candidate, true
+		}
+	}
+	if pkg, ok := f.sources[path.Join(p, "package.json")]; ok {
+		var manifest struct {
+			Main string `json:"main"`
+		}
+		if json.Unmarshal([]byte(pkg), &manifest) == nil && manifest.Main != "" {
+			main := path.Join(p, manifest.Main)
+			for _, candidate := range []string{main, main + ".js", main + ".json", path.Join(main, "index.js")} {
+				if _, ok := f.sources[candidate]; ok {
+					return candidate, true
+				}
+			}
+		}
+	}
+	for _, candidate := range []string{path.Join(p, "index.js"), path.Join(p, "index.json")} {
+		if _, ok := f.sources[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
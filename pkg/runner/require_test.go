@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RequireTestSuite struct {
+	suite.Suite
+	runner *Runner
+}
+
+func TestRequireTestSuite(t *testing.T) {
+	suite.Run(t, new(RequireTestSuite))
+}
+
+func (suite *RequireTestSuite) SetupTest() {
+	r, err := NewRunner("test.js")
+	suite.Require().NoError(err)
+	suite.runner = r
+}
+
+func (suite *RequireTestSuite) TearDownTest() {
+	suite.runner.Close()
+}
+
+func (suite *RequireTestSuite) run(script string) string {
+	val, err := suite.runner.RunScript(context.Background(), script)
+	suite.Require().NoError(err)
+	return val.String()
+}
+
+func (suite *RequireTestSuite) TestRequire() {
+	suite.Require().NoError(suite.runner.SetFiles(map[string]string{
+		"lib/math.js": `
+			exports.add = (a, b) => a + b;
+			exports.file = __filename + " " + __dirname;`,
+		"lib/point.js": `
+			const { add } = require("./math");
+			module.exports = class Point { constructor(x, y) { this.sum = add(x, y); } };`,
+		"config.json":                        `{"name": "rules"}`,
+		"node_modules/lodash/package.json":   `{"main": "./dist/lodash.js"}`,
+		"node_modules/lodash/dist/lodash.js": `exports.identity = (x) => x;`,
+		"node_modules/left-pad/index.js":     `module.exports = (s) => " " + s;`,
+		"lib/node_modules/left-pad/index.js": `module.exports = (s) => "  " + s;`,
+		"lib/pad.js":                         `module.exports = require("left-pad");`,
+	}))
+
+	suite.Equal("3", suite.run(`require("./lib/math.js").add(1, 2)`))
+	suite.Equal("/lib/math.js /lib", suite.run(`require("./lib/math").file`))
+	suite.Equal("7", suite.run(`const Point = require("./lib/point"); new Point(3, 4).sum`))
+	suite.Equal("rules", suite.run(`require("./config").name`))
+	suite.Equal("x", suite.run(`require("lodash").identity("x")`))
+	suite.Equal(" a", suite.run(`require("left-pad")("a")`))
+	suite.Equal("  a", suite.run(`require("./lib/pad")("a")`))
+}
+
+func (suite *RequireTestSuite) TestCache() {
+	suite.Require().NoError(suite.runner.SetFiles(map[string]string{
+		"counter.js": `globalThis.evaluations = (globalThis.evaluations || 0) + 1; exports.n = 0;`,
+		"a.js":       `exports.early = true; const b = require("./b"); exports.fromB = b.sawEarly;`,
+		"b.js":       `exports.sawEarly = require("./a").early;`,
+	}))
+	suite.Equal("2", suite.run(`require("./counter").n++; require("./counter.js").n++; require("./counter").n`))
+	suite.Equal("1", suite.run(`evaluations`))
+	// modules requiring each other see the exports defined so far.
+	suite.Equal("true", suite.run(`require("./a").fromB`))
+
+	// replacing the files discards the evaluated modules.
+	suite.Require().NoError(suite.runner.SetFiles(map[string]string{"counter.js": `exports.n = 10;`}))
+	suite.Equal("10", suite.run(`require("./counter").n`))
+}
+
+func (suite *RequireTestSuite) TestErrors() {
+	suite.Require().NoError(suite.runner.SetFiles(map[string]string{
+		"broken.js": "const x = 1;\nthrow new Error('broken');",
+		"bad.json":  `{`,
+		"stack.js":  "const x = 1;\nexports.stack = new Error().stack;",
+	}))
+	// the line numbers are the line numbers of the file.
+	suite.Contains(suite.run(`require("./stack").stack`), "stack.js:2")
+
+	_, err := suite.runner.RunScript(context.Background(), `require("./missing")`)
+	suite.ErrorContains(err, "cannot find module './missing'")
+	// no access to the real disk.
+	_, err = suite.runner.RunScript(context.Background(), `require("/etc/hostname")`)
+	suite.ErrorContains(err, "cannot find module")
+	_, err = suite.runner.RunScript(context.Background(), `require(1)`)
+	suite.ErrorContains(err, "must be of type string")
+	_, err = suite.runner.RunScript(context.Background(), `require("./bad.json")`)
+	suite.ErrorContains(err, "failed to require /bad.json")
+
+	_, err = suite.runner.RunScript(context.Background(), `require("./broken")`)
+	suite.ErrorContains(err, "broken")
+	// a module failing to evaluate is not cached.
+	_, err = suite.runner.RunScript(context.Background(), `require("./broken")`)
+	suite.ErrorContains(err, "broken")
+}
+
+func (suite *RequireTestSuite) TestNoFiles() {
+	suite.Equal("undefined", suite.run(`typeof require`))
+}
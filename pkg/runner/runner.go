@@ -3,23 +3,65 @@ package runner
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"strings"
+	"time"
 
-	v8 "github.com/stumble/v8go"
+	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
 )
 
-var ErrorTimeout = fmt.Errorf("timeout")
+var (
+	ErrorTimeout      = fmt.Errorf("timeout")
+	ErrCodeTooLarge   = fmt.Errorf("code too large")
+	ErrResultTooLarge = fmt.Errorf("result too large")
+	ErrOutOfMemory    = fmt.Errorf("out of memory")
+)
 
+// Option configures the isolate of a Runner. Options only apply to the runner they are
+// passed to, runners with different options can be used in the same process.
 type Option interface {
-	Apply() error
+	apply(c *config) error
+}
+
+// config is the configuration of the isolate of a runner.
+type config struct {
+	heapSizeMB    uint
+	headroomMB    uint
+	snapshot      *Snapshot
+	scriptCache   *ScriptCacheOption
+	polyfills     bool
+	deterministic bool
+	lockdown      LockdownOption
 }
 
+// MaxHeapSizeOption limits the heap of the isolate, through the create params of the
+// isolate. V8 aborts the whole process once the limit is exceeded, unless the runner has
+// a NearHeapLimitOption.
 type MaxHeapSizeOption struct {
 	HeapSizeMB uint
 }
 
-func (o MaxHeapSizeOption) Apply() error {
-	v8.SetFlags(fmt.Sprintf("--max-heap-size=%d", o.HeapSizeMB))
+func (o MaxHeapSizeOption) apply(c *config) error {
+	c.heapSizeMB = o.HeapSizeMB
+	return nil
+}
+
+// NearHeapLimitOption turns MaxHeapSizeOption into a soft limit: once the heap reaches it,
+// a near-heap-limit callback terminates the execution, which fails with ErrOutOfMemory, and
+// raises the limit by HeadroomMB so that the script can unwind. The runner is then reset to
+// a new isolate and context, which drops the state of the previous scripts, so that the
+// process survives a greedy script. V8 still aborts the process if the script allocates
+// more than HeadroomMB before the termination lands, e.g. in a single huge allocation.
+type NearHeapLimitOption struct {
+	HeadroomMB uint
+}
+
+func (o NearHeapLimitOption) apply(c *config) error {
+	if o.HeadroomMB == 0 {
+		return fmt.Errorf("near heap limit headroom must be positive")
+	}
+	c.headroomMB = o.HeadroomMB
 	return nil
 }
 
@@ -29,33 +71,73 @@ type Runner struct {
 	vm       *v8.Isolate
 	codeCtx  *v8.Context
 	closed   bool
+	config   config
+	scripts  *scriptCache
+	handles  map[string]*compiledScript
+	modules  *modules
+	files    *files
+	// seed seeds the replacements of the non-deterministic APIs, see DeterministicOption.
+	seed *v8.Function
+	// usage is the cumulative usage of the executions, see Usage.
+	usage Usage
+	// dirty is set once the state of the context can no longer be trusted: an execution
+	// has been terminated before it finished, or the context has been reset.
+	dirty bool
 }
 
 // NewRunner creates a new JavaScript runner.
 func NewRunner(fileName string, options ...Option) (*Runner, error) {
 	fileName = strings.TrimSuffix(fileName, ".js") + ".js"
+	var c config
 	for _, opt := range options {
-		err := opt.Apply()
+		err := opt.apply(&c)
 		if err != nil {
 			return nil, err
 		}
 	}
-	vm := v8.NewIsolate()
-	codeCtx := v8.NewContext(vm)
-	return &Runner{
+	if c.headroomMB > 0 && c.heapSizeMB == 0 {
+		return nil, fmt.Errorf("near heap limit requires a max heap size")
+	}
+	vm := newIsolate(c)
+	r := &Runner{
 		fileName: fileName,
 		vm:       vm,
-		codeCtx:  codeCtx,
-	}, nil
+		codeCtx:  newContext(vm, c.lockdown.NoCodeGeneration),
+		config:   c,
+		scripts:  newScriptCache(c.scriptCache),
+	}
+	if err := r.boot(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// boot defines the polyfills and the deterministic APIs of the runner in its context,
+// and locks it down. The state of the snapshot of the runner, if any, is already there.
+func (r *Runner) boot() error {
+	if err := r.definePolyfills(); err != nil {
+		return err
+	}
+	if err := r.defineDeterminism(); err != nil {
+		return err
+	}
+	return r.applyLockdown()
 }
 
-// Close free resources.
+// Close free resources. It is safe to close twice.
 func (r *Runner) Close() {
+	if r.closed {
+		return
+	}
 	r.codeCtx.Close()
 	r.vm.Dispose()
 	r.closed = true
 }
 
+// RunScript runs script in the context of the runner. If ctx is done before the script
+// returns, the execution is terminated and ErrorTimeout is returned. The runner stays
+// usable, with the state left by the terminated script.
 func (r *Runner) RunScript(ctx context.Context, script string) (*v8.Value, error) {
 	if r.closed {
 		return nil, fmt.Errorf("runner is closed")
@@ -67,32 +149,221 @@ func (r *Runner) RunScript(ctx context.Context, script string) (*v8.Value, error
 	return val, nil
 }
 
+// Usage is the cumulative resource usage of the executions of a runner.
+type Usage struct {
+	// CPUTime is the CPU time of the executions, measured on the thread running them.
+	CPUTime time.Duration
+	// CompileTime is the time spent compiling scripts, modules and handles.
+	CompileTime time.Duration
+	// GCCount is the number of garbage collections of the isolates of the runner.
+	GCCount uint64
+}
+
+// Usage returns the cumulative resource usage of the executions of the runner.
+func (r *Runner) Usage() Usage {
+	usage := r.usage
+	if !r.closed {
+		usage.GCCount += r.vm.GCCount()
+	}
+	return usage
+}
+
+// heapStatistics returns the heap statistics of the isolate, the zero statistics once closed.
+// It must not be called while a script is running.
+func (r *Runner) heapStatistics() v8.HeapStatistics {
+	if r.closed {
+		return v8.HeapStatistics{}
+	}
+	return r.vm.GetHeapStatistics()
+}
+
+// timeCompile adds the time elapsed since start to the compile time of the runner.
+func (r *Runner) timeCompile(start time.Time) {
+	r.usage.CompileTime += time.Since(start)
+}
+
 func (r *Runner) CodeCtx() *v8.Context {
 	return r.codeCtx
 }
 
+// Call calls fn with args. If ctx is done before fn returns, the execution is
+// terminated and ErrorTimeout is returned, like RunScript.
+func (r *Runner) Call(ctx context.Context, fn *v8.Function, args ...v8.Valuer) (*v8.Value, error) {
+	if r.closed {
+		return nil, fmt.Errorf("runner is closed")
+	}
+	return r.execute(ctx, func() (*v8.Value, error) {
+		val, err := fn.Call(v8.Undefined(r.vm), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call function because: %w", err)
+		}
+		return val, nil
+	})
+}
+
 func (r *Runner) runScript(ctx context.Context, script string) (*v8.Value, error) {
-	vals := make(chan *v8.Value, 1)
-	errs := make(chan error, 1)
-	go func() {
-		val, err := r.codeCtx.RunScript(script, r.fileName)
+	if r.scripts != nil {
+		return r.runCachedScript(ctx, script)
+	}
+	return r.execute(ctx, func() (*v8.Value, error) {
+		start := time.Now()
+		compiled, err := r.vm.CompileUnboundScript(script, r.fileName, v8.CompileOptions{})
+		r.timeCompile(start)
 		if err != nil {
-			errs <- fmt.Errorf("failed to run script because: %w", err)
-			return
+			return nil, fmt.Errorf("failed to run script because: %w", err)
 		}
-		vals <- val
-	}()
+		val, err := compiled.Run(r.codeCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run script because: %w", err)
+		}
+		return val, nil
+	})
+}
 
-	// Do not return error details in error.
-	select {
-	case val := <-vals:
+// runCachedScript runs script like runScript, but compiles it through the script cache.
+func (r *Runner) runCachedScript(ctx context.Context, script string) (*v8.Value, error) {
+	var cached *cachedScript
+	val, err := r.execute(ctx, func() (*v8.Value, error) {
+		var err error
+		start := time.Now()
+		cached, err = r.scripts.compile(r.vm, r.fileName, script)
+		r.timeCompile(start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run script because: %w", err)
+		}
+		val, err := cached.script.Run(r.codeCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run script because: %w", err)
+		}
 		return val, nil
-	case err := <-errs:
-		return nil, err
-	case <-ctx.Done():
+	})
+	if err == nil && !r.closed {
+		r.scripts.persist(cached)
+	}
+	return val, err
+}
+
+// ScriptCacheStats returns the cumulative stats of the script cache,
+// which are all zero without ScriptCacheOption.
+func (r *Runner) ScriptCacheStats() types.ScriptCacheStats {
+	if r.scripts == nil {
+		return types.ScriptCacheStats{}
+	}
+	return r.scripts.stats
+}
+
+// execResult is the outcome of fn in execute, and the CPU time it consumed.
+type execResult struct {
+	val *v8.Value
+	err error
+	cpu time.Duration
+}
+
+// execute runs fn, and resets the runner if the execution has been terminated by the
+// near-heap-limit callback of NearHeapLimitOption.
+func (r *Runner) execute(ctx context.Context, fn func() (*v8.Value, error)) (*v8.Value, error) {
+	val, err := r.run(ctx, fn)
+	if r.vm.NearHeapLimitReached() {
+		r.reset()
+		return nil, fmt.Errorf("%w: heap exceeds %d MB", ErrOutOfMemory, r.config.heapSizeMB)
+	}
+	return val, err
+}
+
+// reset replaces the isolate and the context of the runner, so that the memory of the
+// previous scripts is released at once. The values of the previous isolate must not be used.
+func (r *Runner) reset() {
+	r.usage.GCCount += r.vm.GCCount()
+	r.codeCtx.Close()
+	r.vm.Dispose()
+	r.vm = newIsolate(r.config)
+	r.codeCtx = newContext(r.vm, r.config.lockdown.NoCodeGeneration)
+	r.dirty = true
+	if r.scripts != nil {
+		r.scripts.clear()
+	}
+	for _, s := range r.handles {
+		s.script = nil // compiled again when it is run
+	}
+	if r.modules != nil {
+		r.modules.loaded = nil // evaluated again when imported
+		r.codeCtx.SetModuleImporter(r.importDynamic)
+	}
+	if r.files != nil {
+		r.files.makeRequire = nil // created again with require
+	}
+	err := r.boot()
+	if err == nil {
+		err = r.defineRequire()
+	}
+	if err != nil {
+		// the runner booted before, it can only fail if it is out of memory
+		// right away, so the runner is closed instead.
+		r.Close()
+	}
+}
+
+// run runs fn in a separate goroutine, and terminates the execution if ctx is done first,
+// or if it exceeds the CPU budget of ctx.
+func (r *Runner) run(ctx context.Context, fn func() (*v8.Value, error)) (*v8.Value, error) {
+	budget := cpuBudgetFrom(ctx)
+	if budget.exhausted() {
+		return nil, budget.err()
+	}
+
+	clocks := make(chan threadClock, 1)
+	results := make(chan execResult, 1)
+	go func() {
+		// keep the execution on one thread, so that its CPU clock can be read.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		clock := currentThreadClock()
+		start := clock.Now()
+		clocks <- clock
+		val, err := fn()
+		results <- execResult{val: val, err: err, cpu: clock.Now() - start}
+	}()
+	clock := <-clocks
+	start := clock.Now()
+
+	var tick <-chan time.Time
+	if budget != nil {
+		ticker := time.NewTicker(budget.pollInterval())
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// terminate terminates the execution, and returns err unless it finished anyway.
+	terminate := func(err func(res execResult) error) (*v8.Value, error) {
 		r.vm.TerminateExecution() // terminate the execution
-		r.Close()                 // close the runner once the execution is terminated
-		err := <-errs             // will get a termination error back from the running script
-		return nil, fmt.Errorf("%w: %s", ErrorTimeout, err)
+		res := <-results
+		budget.consume(res.cpu)
+		r.usage.CPUTime += res.cpu
+		if res.err == nil { // finished before being terminated
+			return res.val, nil
+		}
+		r.dirty = true
+		return nil, err(res)
+	}
+
+	// Do not return error details in error.
+	for {
+		select {
+		case res := <-results:
+			budget.consume(res.cpu)
+			r.usage.CPUTime += res.cpu
+			return res.val, res.err
+		case <-tick:
+			if clock.Now()-start > budget.remaining {
+				return terminate(func(execResult) error { return budget.err() })
+			}
+		case <-ctx.Done():
+			// The termination ends with the terminated script, so unlike V8 this fork
+			// of v8go needs no CancelTerminateExecution to keep the isolate usable.
+			return terminate(func(res execResult) error {
+				// will get a termination error back from the running script
+				return fmt.Errorf("%w: %s", ErrorTimeout, res.err)
+			})
+		}
 	}
 }
@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/stumble/v8runner/pkg/types"
+)
+
+type RunnerTestSuite struct {
+	suite.Suite
+}
+
+func TestRunnerTestSuite(t *testing.T) {
+	suite.Run(t, new(RunnerTestSuite))
+}
+
+func (suite *RunnerTestSuite) TestMaxHeapSize() {
+	small, err := NewRunner("test.js", MaxHeapSizeOption{HeapSizeMB: 8})
+	suite.Require().NoError(err)
+	defer small.Close()
+	large, err := NewRunner("test.js", MaxHeapSizeOption{HeapSizeMB: 32})
+	suite.Require().NoError(err)
+	defer large.Close()
+	// each runner keeps its own limit
+	suite.Equal(uint64(8), small.vm.GetHeapStatistics().HeapSizeLimit>>20)
+	suite.Equal(uint64(32), large.vm.GetHeapStatistics().HeapSizeLimit>>20)
+
+	near, err := NewRunner("test.js", MaxHeapSizeOption{HeapSizeMB: 8}, NearHeapLimitOption{HeadroomMB: 4})
+	suite.Require().NoError(err)
+	defer near.Close()
+	suite.Equal(uint64(8), near.vm.GetHeapStatistics().HeapSizeLimit>>20)
+
+	_, err = NewRunner("test.js", NearHeapLimitOption{HeadroomMB: 4})
+	suite.Error(err)
+}
+
+func (suite *RunnerTestSuite) TestNearHeapLimit() {
+	r, err := NewRunner("test.js", MaxHeapSizeOption{HeapSizeMB: 16}, NearHeapLimitOption{HeadroomMB: 16})
+	suite.Require().NoError(err)
+	defer r.Close()
+	_, err = r.RunScript(context.Background(), "var kept = 1")
+	suite.Require().NoError(err)
+	// terminated by the callback, instead of V8 aborting the process
+	_, err = r.RunScript(context.Background(), `
+  const hog = [];
+  while (true) {
+    hog.push(new Array(1024 * 64).fill(1));
+  }
+`)
+	suite.ErrorIs(err, ErrOutOfMemory)
+	// the runner is reset, and can raise its limit again
+	val, err := r.RunScript(context.Background(), "typeof kept")
+	suite.Require().NoError(err)
+	suite.Equal("undefined", val.String())
+	_, err = r.RunScript(context.Background(), "const hog = []; while (true) { hog.push(new Array(1024 * 64).fill(1)); }")
+	suite.ErrorIs(err, ErrOutOfMemory)
+}
+
+func (suite *RunnerTestSuite) TestSnapshot() {
+	snapshot, err := CreateSnapshot("lib.js", "const lib = { double: (x) => x * 2 }; var booted = 0; globalThis.runs = (globalThis.runs || 0) + 1;")
+	suite.Require().NoError(err)
+	buf := &bytes.Buffer{}
+	suite.Require().NoError(snapshot.Write(buf))
+	snapshot, err = ReadSnapshot(buf)
+	suite.Require().NoError(err)
+
+	r, err := NewRunner("test.js", SnapshotOption{Snapshot: snapshot}, MaxHeapSizeOption{HeapSizeMB: 16}, PolyfillsOption{})
+	suite.Require().NoError(err)
+	defer r.Close()
+	val, err := r.RunScript(context.Background(), "booted++; lib.double(21)")
+	suite.NoError(err)
+	suite.Equal("42", val.String())
+	// the bootstrap script is not run again
+	val, err = r.RunScript(context.Background(), "runs")
+	suite.NoError(err)
+	suite.Equal("1", val.String())
+	val, err = r.RunScript(context.Background(), "typeof TextEncoder")
+	suite.NoError(err)
+	suite.Equal("function", val.String())
+
+	// a runner reset by the near heap limit boots again
+	r.reset()
+	val, err = r.RunScript(context.Background(), "booted")
+	suite.NoError(err)
+	suite.Equal("0", val.String())
+
+	_, err = CreateSnapshot("lib.js", "throw new Error('boom')")
+	suite.ErrorContains(err, "boom")
+	_, err = ReadSnapshot(strings.NewReader("not a snapshot"))
+	suite.Error(err)
+	_, err = ReadSnapshot(strings.NewReader(strings.Repeat("not a snapshot", 100)))
+	suite.Error(err)
+}
+
+func (suite *RunnerTestSuite) TestScriptCache() {
+	dir := suite.T().TempDir()
+	r, err := NewRunner("test.js", ScriptCacheOption{Size: 2, Dir: dir})
+	suite.Require().NoError(err)
+	defer r.Close()
+	run := func(r *Runner, code string) {
+		_, err := r.RunScript(context.Background(), code)
+		suite.Require().NoError(err)
+	}
+
+	run(r, "1+1")
+	run(r, "1+1")
+	suite.Equal(types.ScriptCacheStats{Hits: 1, Misses: 1}, r.ScriptCacheStats())
+	// the least recently used script is evicted
+	run(r, "1+2")
+	run(r, "1+3")
+	run(r, "1+1")
+	suite.Equal(types.ScriptCacheStats{Hits: 1, Misses: 3, DiskHits: 1}, r.ScriptCacheStats())
+	files, err := os.ReadDir(dir)
+	suite.Require().NoError(err)
+	suite.Len(files, 3)
+
+	// a new runner compiles the scripts from the code cache on disk
+	other, err := NewRunner("test.js", ScriptCacheOption{Size: 2, Dir: dir})
+	suite.Require().NoError(err)
+	defer other.Close()
+	run(other, "1+2")
+	suite.Equal(types.ScriptCacheStats{DiskHits: 1}, other.ScriptCacheStats())
+}
+
+func (suite *RunnerTestSuite) TestCompile() {
+	r, err := NewRunner("test.js")
+	suite.Require().NoError(err)
+	defer r.Close()
+
+	err = r.Compile(context.Background(), "broken", "let x = ;")
+	suite.ErrorContains(err, "SyntaxError")
+	_, err = r.RunCompiled(context.Background(), "broken")
+	suite.ErrorIs(err, ErrUnknownScript)
+
+	suite.Require().NoError(r.Compile(context.Background(), "count", "var n = (globalThis.n || 0) + 1; n"))
+	for _, want := range []string{"1", "2"} {
+		val, err := r.RunCompiled(context.Background(), "count")
+		suite.NoError(err)
+		suite.Equal(want, val.String())
+	}
+	// the script survives a reset
+	r.reset()
+	val, err := r.RunCompiled(context.Background(), "count")
+	suite.NoError(err)
+	suite.Equal("1", val.String())
+}
+
+// BenchmarkBoot compares booting a runner that runs a large library from its source with
+// booting it from a snapshot.
+func BenchmarkBoot(b *testing.B) {
+	lib := &strings.Builder{}
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(lib, "function f%d(x) { const o = { a: x, b: [x, x + %d] }; return o.b.reduce((s, v) => s + v, o.a); }\n", i, i)
+	}
+	lib.WriteString("var libReady = f1999(1);\n")
+	snapshot, err := CreateSnapshot("lib.js", lib.String())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("source", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r, err := NewRunner("test.js")
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := r.RunScript(context.Background(), lib.String()); err != nil {
+				b.Fatal(err)
+			}
+			r.Close()
+		}
+	})
+	b.Run("snapshot", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r, err := NewRunner("test.js", SnapshotOption{Snapshot: snapshot})
+			if err != nil {
+				b.Fatal(err)
+			}
+			r.Close()
+		}
+	})
+}
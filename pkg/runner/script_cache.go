@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// ScriptCacheOption caches the scripts compiled by RunScript, so that running the same
+// code again skips parsing and compiling it. Size is the number of scripts kept in memory,
+// the least recently used are evicted first. If Dir is set, the V8 code cache of each
+// script is also written to Dir, so that a new runner using the same Dir compiles the
+// scripts it has never seen from their code cache.
+type ScriptCacheOption struct {
+	Size int
+	Dir  string
+}
+
+func (o ScriptCacheOption) apply(c *config) error {
+	if o.Size <= 0 {
+		return fmt.Errorf("invalid script cache size: %d", o.Size)
+	}
+	c.scriptCache = &o
+	return nil
+}
+
+// scriptCache is an LRU of the scripts compiled in the isolate of a runner.
+type scriptCache struct {
+	size  int
+	dir   string
+	lru   *list.List // of *cachedScript, the most recently used first
+	byKey map[string]*list.Element
+	stats types.ScriptCacheStats
+}
+
+type cachedScript struct {
+	key    string
+	script *v8.UnboundScript
+	// persisted is set once the code cache of the script is in dir.
+	persisted bool
+}
+
+func newScriptCache(o *ScriptCacheOption) *scriptCache {
+	if o == nil {
+		return nil
+	}
+	return &scriptCache{
+		size:  o.Size,
+		dir:   o.Dir,
+		lru:   list.New(),
+		byKey: make(map[string]*list.Element),
+	}
+}
+
+// scriptKey identifies a script compiled by a version of V8, so that the code cache
+// of another version is never looked up.
+func scriptKey(origin, code string) string {
+	h := sha256.New()
+	for _, s := range []string{v8.Version(), origin, code} {
+		_, _ = fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// compile returns the script of code, from the cache if possible.
+func (c *scriptCache) compile(vm *v8.Isolate, origin, code string) (*cachedScript, error) {
+	key := scriptKey(origin, code)
+	if e, ok := c.byKey[key]; ok {
+		c.lru.MoveToFront(e)
+		c.stats.Hits++
+		return e.Value.(*cachedScript), nil
+	}
+
+	opts := v8.CompileOptions{}
+	cached := c.readCodeCache(key)
+	if cached != nil {
+		opts.CachedData = cached
+	}
+	script, err := vm.CompileUnboundScript(code, origin, opts)
+	if err != nil {
+		c.stats.Misses++
+		return nil, err
+	}
+	if cached != nil && !cached.Rejected {
+		c.stats.DiskHits++
+	} else {
+		c.stats.Misses++
+	}
+	s := &cachedScript{key: key, script: script, persisted: cached != nil && !cached.Rejected}
+	c.byKey[key] = c.lru.PushFront(s)
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.byKey, oldest.Value.(*cachedScript).key)
+	}
+	return s, nil
+}
+
+func (c *scriptCache) path(key string) string {
+	return filepath.Join(c.dir, key+".cache")
+}
+
+func (c *scriptCache) readCodeCache(key string) *v8.CompilerCachedData {
+	if c.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return &v8.CompilerCachedData{Bytes: data}
+}
+
+// persist writes the code cache of s to dir. It is called once the script has run,
+// so that the code cache also covers the functions compiled while running it.
+// Failing to write the cache only costs a compilation later, so errors are ignored.
+func (c *scriptCache) persist(s *cachedScript) {
+	if c.dir == "" || s.persisted {
+		return
+	}
+	s.persisted = true
+	data := s.script.CreateCodeCache().Bytes
+	if len(data) == 0 {
+		return
+	}
+	// write to a temporary file first, so that other processes never read a partial cache.
+	tmp, err := os.CreateTemp(c.dir, s.key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(s.key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// clear drops the scripts compiled in a previous isolate, the stats are kept.
+func (c *scriptCache) clear() {
+	c.lru.Init()
+	c.byKey = make(map[string]*list.Element)
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+// Snapshot is a V8 startup snapshot of an isolate that has run a bootstrap script. A runner
+// booted from it starts with the state left by the script, e.g. the libraries it defines,
+// without parsing, compiling or running the script again.
+type Snapshot struct {
+	blob []byte
+}
+
+// CreateSnapshot runs the bootstrap script in a new isolate, and creates a snapshot of it.
+// The script runs in a bare context: the polyfills, the deterministic APIs and the lockdown
+// of the runners booted from the snapshot are applied after it, and it cannot use require.
+func CreateSnapshot(origin, source string) (*Snapshot, error) {
+	blob, err := v8.CreateSnapshot(source, origin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run bootstrap because: %w", err)
+	}
+	return &Snapshot{blob: blob}, nil
+}
+
+// ReadSnapshotFile reads a snapshot written by WriteFile.
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	// #nosec G304 -- the path of the snapshot is not user input
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ReadSnapshot(f)
+}
+
+// ReadSnapshot reads a snapshot written by Write. It fails if the snapshot was created
+// by another version of V8, which cannot boot from it.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot because: %w", err)
+	}
+	if !v8.ValidSnapshot(blob) {
+		return nil, fmt.Errorf("invalid snapshot, or created by another version of V8 than %s", v8.Version())
+	}
+	return &Snapshot{blob: blob}, nil
+}
+
+// Write writes the snapshot to w.
+func (s *Snapshot) Write(w io.Writer) error {
+	_, err := w.Write(s.blob)
+	return err
+}
+
+// WriteFile writes the snapshot to the file at path.
+func (s *Snapshot) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SnapshotOption boots the isolate of the runner from a snapshot. A runner reset by
+// NearHeapLimitOption boots from it again.
+type SnapshotOption struct {
+	Snapshot *Snapshot
+}
+
+func (o SnapshotOption) apply(c *config) error {
+	if o.Snapshot == nil {
+		return fmt.Errorf("snapshot is nil")
+	}
+	c.snapshot = o.Snapshot
+	return nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stumble/v8runner/pkg/types"
+	v8 "github.com/stumble/v8runner/third_party/v8go"
+)
+
+var ErrStreamTooLarge = fmt.Errorf("stream too large")
+
+// streamResult runs req.Code and sends each value of the returned iterable, e.g. an
+// array or a generator, as its own response, so that a large output never has to be
+// stringified as a whole. The stream ends with a response without More, which carries
+// the error if the iteration failed.
+func (r *ReaderRunner) streamResult(
+	ctx context.Context,
+	runner *Runner,
+	req types.RunCodeRequest,
+	send func(types.RunCodeResponse) error,
+) error {
+	val, err := runner.RunScript(ctx, req.Code)
+	if err != nil {
+		return send(errResult(req.ID, err))
+	}
+	it, err := iterator(runner, val)
+	if err != nil {
+		return send(errResult(req.ID, err))
+	}
+
+	total := 0
+	for {
+		res, err := runner.execute(ctx, func() (*v8.Value, error) {
+			return it.MethodCall("next")
+		})
+		if err != nil {
+			return send(errResult(req.ID, fmt.Errorf("failed to iterate because: %w", err)))
+		}
+		next, err := res.AsObject()
+		if err != nil {
+			return send(errResult(req.ID, fmt.Errorf("failed to iterate because: %w", err)))
+		}
+		done, err := next.Get("done")
+		if err != nil {
+			return send(errResult(req.ID, fmt.Errorf("failed to iterate because: %w", err)))
+		}
+		if done.Boolean() {
+			return send(nilResult(req.ID))
+		}
+		item, err := next.Get("value")
+		if err != nil {
+			return send(errResult(req.ID, fmt.Errorf("failed to iterate because: %w", err)))
+		}
+		jsonStr, err := v8.JSONStringify(runner.CodeCtx(), item)
+		if err != nil {
+			return send(errResult(req.ID, err))
+		}
+		if err := r.checkResultSize(len(jsonStr)); err != nil {
+			return send(errResult(req.ID, err))
+		}
+		total += len(jsonStr)
+		if req.MaxStreamSize > 0 && total > req.MaxStreamSize {
+			return send(errResult(req.ID,
+				fmt.Errorf("%w: exceeds %d bytes", ErrStreamTooLarge, req.MaxStreamSize)))
+		}
+		if err := send(types.RunCodeResponse{
+			ID:     req.ID,
+			Result: &jsonStr,
+			More:   true,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// iterator returns val[Symbol.iterator]().
+func iterator(runner *Runner, val *v8.Value) (*v8.Object, error) {
+	obj, err := val.AsObject()
+	if err != nil {
+		return nil, fmt.Errorf("result is not iterable: %w", err)
+	}
+	iterFn, err := obj.GetSymbol(v8.SymbolIterator(runner.vm))
+	if err != nil {
+		return nil, fmt.Errorf("result is not iterable: %w", err)
+	}
+	fn, err := iterFn.AsFunction()
+	if err != nil {
+		return nil, fmt.Errorf("result is not iterable: %w", err)
+	}
+	it, err := fn.Call(obj)
+	if err != nil {
+		return nil, fmt.Errorf("result is not iterable: %w", err)
+	}
+	return it.AsObject()
+}
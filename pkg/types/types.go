@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/gob"
 	"io"
+	"time"
 )
 
 type RtnValType string
@@ -10,18 +11,182 @@ type RtnValType string
 const (
 	RtnValueTypeNil  RtnValType = "nil"
 	RtnValueTypeJSON RtnValType = "json"
+	// RtnValueTypeStream iterates the returned iterable and sends each value as JSON
+	// in its own response with More set, followed by a final response without More.
+	RtnValueTypeStream RtnValType = "stream"
+)
+
+// ErrCode identifies the kind of error of a response, so that the caller can tell
+// errors apart without parsing the message. It is empty for other errors.
+type ErrCode string
+
+const (
+	// ErrCodeTimeout is returned when a request exceeds its Deadline or Timeout.
+	ErrCodeTimeout        ErrCode = "timeout"
+	ErrCodeStreamTooLarge ErrCode = "stream_too_large"
+	ErrCodeCodeTooLarge   ErrCode = "code_too_large"
+	ErrCodeResultTooLarge ErrCode = "result_too_large"
+	// ErrCodeCPUBudgetExceeded is returned when a request exceeds its CPUBudget.
+	ErrCodeCPUBudgetExceeded ErrCode = "cpu_budget_exceeded"
+	// ErrCodeOutOfMemory is returned when a request exceeds the heap limit. The runner
+	// is then reset, and the state of the previous requests is lost.
+	ErrCodeOutOfMemory ErrCode = "out_of_memory"
+	// ErrCodeUnknownScript is returned when no script is registered under the Name of a request.
+	ErrCodeUnknownScript ErrCode = "unknown_script"
+	// ErrCodeModuleNotFound is returned when a module imports a module that is not registered.
+	ErrCodeModuleNotFound ErrCode = "module_not_found"
+)
+
+type ReqType string
+
+const (
+	// ReqTypeRun runs Code and returns its completion value. An empty type is ReqTypeRun.
+	ReqTypeRun ReqType = "run"
+	// ReqTypeMap evaluates Code to a function and calls it once per item of Inputs.
+	ReqTypeMap ReqType = "map"
+	// ReqTypeCompile compiles Code without running it, and registers it under Name.
+	ReqTypeCompile ReqType = "compile"
+	// ReqTypeRunCompiled runs the script registered under Name, Code is ignored.
+	ReqTypeRunCompiled ReqType = "run_compiled"
+	// ReqTypeModules registers the ES modules of Modules.
+	ReqTypeModules ReqType = "modules"
+	// ReqTypeCallModule calls the function exported as Code by the module registered as
+	// Name, with the JSON encoded arguments of Inputs.
+	ReqTypeCallModule ReqType = "call_module"
+	// ReqTypeFiles replaces the virtual filesystem of the CommonJS modules by Files.
+	ReqTypeFiles ReqType = "files"
+	// ReqTypePing is responded to right away, to check that the runner is responsive.
+	ReqTypePing ReqType = "ping"
 )
 
 type RunCodeRequest struct {
 	ID           string     `json:"id"`
+	Type         ReqType    `json:"type,omitempty"`
 	Code         string     `json:"code"`
 	ResponseType RtnValType `json:"responseType"`
+	// Name is the name of the script of a compile or run_compiled request,
+	// and the specifier of the module of a call_module request.
+	Name string `json:"name,omitempty"`
+	// Modules are the sources of the ES modules of a modules request, by specifier.
+	Modules map[string]string `json:"modules,omitempty"`
+	// Files are the contents of the files of a files request, by path.
+	Files map[string]string `json:"files,omitempty"`
+
+	// Deadline terminates the execution of the request when reached, the zero value means
+	// no deadline. A request received after its deadline fails without being executed.
+	// The caller and the runner run on the same host, so they share the clock.
+	Deadline time.Time `json:"deadline,omitempty"`
+
+	// Inputs are the JSON encoded arguments of a map or call_module request.
+	Inputs []string `json:"inputs,omitempty"`
+	// ItemTimeout bounds each call of a map request, 0 means no limit.
+	ItemTimeout time.Duration `json:"itemTimeout,omitempty"`
+	// Timeout bounds a map request as a whole, 0 means no limit.
+	// Items not started before the timeout fail without being called.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// MaxStreamSize bounds the total size of the values of a stream response, 0 means no limit.
+	MaxStreamSize int `json:"maxStreamSize,omitempty"`
+	// CPUBudget bounds the CPU time spent executing the request, 0 means no limit.
+	CPUBudget time.Duration `json:"cpuBudget,omitempty"`
+	// Determinism seeds the random numbers and sets the clock of a deterministic runner.
+	Determinism *Determinism `json:"determinism,omitempty"`
+	// Stats requests the ExecStats of the request in its last response.
+	Stats bool `json:"stats,omitempty"`
+	// TraceID is the trace of the request in the caller, written to the trace log of the
+	// runner when the request starts, see TraceLogPrefix.
+	TraceID string `json:"traceId,omitempty"`
+}
+
+// TraceLogPrefix starts the line written to stderr by the runner before each request,
+// followed by the TraceID of the request, so that the lines written after it are
+// attributed to the request.
+const TraceLogPrefix = "v8runner trace_id="
+
+// CodeSize is the size of the code of the request, including the sources of its modules
+// and files.
+func (r RunCodeRequest) CodeSize() int {
+	size := len(r.Code)
+	for _, source := range r.Modules {
+		size += len(source)
+	}
+	for _, source := range r.Files {
+		size += len(source)
+	}
+	return size
 }
 
 type RunCodeResponse struct {
-	ID     string  `json:"id"`
-	Error  *string `json:"error,omitempty"`
-	Result *string `json:"result,omitempty"`
+	ID        string  `json:"id"`
+	Error     *string `json:"error,omitempty"`
+	ErrorCode ErrCode `json:"errorCode,omitempty"`
+	Result    *string `json:"result,omitempty"`
+	// More is set on every response of a stream but the last one.
+	More bool `json:"more,omitempty"`
+	// Items are the per-item results of a map request, in the order of Inputs.
+	Items []ItemResult `json:"items,omitempty"`
+	// ScriptCache is how the scripts of the request were compiled, nil if the runner
+	// has no script cache. It is set on the last response of a stream.
+	ScriptCache *ScriptCacheStats `json:"scriptCache,omitempty"`
+	// Stats are the execution stats of the request, set on its last response if requested.
+	Stats *ExecStats `json:"stats,omitempty"`
+}
+
+// ScriptCacheStats counts the scripts found in the script cache of a runner (Hits),
+// compiled from a code cache on disk (DiskHits), and compiled from source (Misses).
+type ScriptCacheStats struct {
+	Hits     int `json:"hits"`
+	DiskHits int `json:"diskHits"`
+	Misses   int `json:"misses"`
+}
+
+// Add returns the sum of s and o.
+func (s ScriptCacheStats) Add(o ScriptCacheStats) ScriptCacheStats {
+	return ScriptCacheStats{Hits: s.Hits + o.Hits, DiskHits: s.DiskHits + o.DiskHits, Misses: s.Misses + o.Misses}
+}
+
+// Sub returns the difference of s and o.
+func (s ScriptCacheStats) Sub(o ScriptCacheStats) ScriptCacheStats {
+	return ScriptCacheStats{Hits: s.Hits - o.Hits, DiskHits: s.DiskHits - o.DiskHits, Misses: s.Misses - o.Misses}
+}
+
+// ExecStats are the execution stats of a request.
+type ExecStats struct {
+	// WallTime is the time spent handling the request, from its decoding to its response.
+	WallTime time.Duration `json:"wallTime"`
+	// CPUTime is the CPU time spent executing the scripts of the request, compiling included.
+	CPUTime time.Duration `json:"cpuTime"`
+	// CompileTime is the time spent compiling the scripts of the request.
+	CompileTime time.Duration `json:"compileTime"`
+	// GCCount is the number of garbage collections during the request, minor ones included.
+	GCCount uint64 `json:"gcCount"`
+	// HeapUsedBefore and HeapUsedAfter are the heap used before and after the request, in bytes.
+	HeapUsedBefore uint64 `json:"heapUsedBefore"`
+	HeapUsedAfter  uint64 `json:"heapUsedAfter"`
+	// TotalHeapSize is the size of the heap after the request, in bytes.
+	TotalHeapSize uint64 `json:"totalHeapSize"`
+	// ExternalMemory is the memory held by V8 outside of the heap after the request,
+	// e.g. the backing stores of ArrayBuffers, in bytes.
+	ExternalMemory uint64 `json:"externalMemory"`
+}
+
+// Determinism is the source of the non-deterministic APIs of a deterministic runner, so that
+// evaluating a request again with the same Determinism yields the same result.
+type Determinism struct {
+	// Seed seeds Math.random.
+	Seed int64 `json:"seed"`
+	// Clock is the time of Date.now and new Date() when the request starts, the Unix epoch
+	// if zero. performance.now is the time elapsed since Clock.
+	Clock time.Time `json:"clock,omitempty"`
+	// ClockStep is how much the clock advances each time it is read, in whole
+	// milliseconds, 0 for a fixed clock.
+	ClockStep time.Duration `json:"clockStep,omitempty"`
+}
+
+// ItemResult is the result of one call of a map request, either Error or Result is set.
+type ItemResult struct {
+	Error     *string `json:"error,omitempty"`
+	ErrorCode ErrCode `json:"errorCode,omitempty"`
+	Result    *string `json:"result,omitempty"`
 }
 
 // NewRunCodeRequestEncoder creates a new encoder for RunCodeRequest.
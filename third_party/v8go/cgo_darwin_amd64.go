@@ -0,0 +1,7 @@
+// Generated by deps/update_cgo.py.
+//go:build cgo && darwin && amd64
+
+package v8go
+
+// Ensure the appropriate cgo library is pulled in.
+import _ "github.com/stumble/v8go/deps/darwin_amd64"
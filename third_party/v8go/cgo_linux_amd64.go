@@ -0,0 +1,7 @@
+// Generated by deps/update_cgo.py.
+//go:build cgo && linux && amd64
+
+package v8go
+
+// Ensure the appropriate cgo library is pulled in.
+import _ "github.com/stumble/v8go/deps/linux_amd64"
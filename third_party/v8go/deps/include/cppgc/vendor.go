@@ -0,0 +1,3 @@
+// Generated by deps/upgrade_v8.py, DO NOT REMOVE/EDIT MANUALLY.
+// Package cppgc is required to provide support for vendoring modules
+package cppgc
@@ -0,0 +1,8 @@
+// Generated by deps/upgrade_v8.py, DO NOT REMOVE/EDIT MANUALLY.
+// Package include is required to provide support for vendoring modules
+package include
+
+import (
+	_ "github.com/stumble/v8go/deps/include/cppgc"
+	_ "github.com/stumble/v8go/deps/include/libplatform"
+)
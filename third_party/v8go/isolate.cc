@@ -0,0 +1,155 @@
+#include "deps/include/v8-context.h"
+#include "deps/include/v8-initialization.h"
+#include "deps/include/v8-locker.h"
+#include "deps/include/v8-platform.h"
+
+#include "context.h"
+#include "isolate.h"
+#include "module.h"
+#include "libplatform/libplatform.h"
+
+using namespace v8;
+
+auto default_platform = platform::NewDefaultPlatform();
+ArrayBuffer::Allocator* default_allocator;
+
+extern "C" {
+
+/********** Isolate **********/
+
+#define ISOLATE_SCOPE(iso)           \
+  Locker locker(iso);                \
+  Isolate::Scope isolate_scope(iso); \
+  HandleScope handle_scope(iso);
+
+void Init() {
+#ifdef _WIN32
+  V8::InitializeExternalStartupData(".");
+#endif
+  V8::InitializePlatform(default_platform.get());
+  V8::Initialize();
+
+  default_allocator = ArrayBuffer::Allocator::NewDefaultAllocator();
+  return;
+}
+
+static inline m_isolate_data* isolateData(Isolate* iso) {
+  return static_cast<m_isolate_data*>(iso->GetData(1));
+}
+
+// TerminateNearHeapLimit terminates the execution once the heap reaches its limit,
+// and raises the limit by the headroom so that the execution can unwind. It does
+// it only once: if the heap reaches the raised limit, V8 aborts the process.
+static size_t TerminateNearHeapLimit(void* data,
+                                     size_t current_heap_limit,
+                                     size_t initial_heap_limit) {
+  auto iso_data = static_cast<m_isolate_data*>(data);
+  if (iso_data->near_heap_limit_reached.exchange(true)) {
+    return current_heap_limit;
+  }
+  iso_data->iso->TerminateExecution();
+  return current_heap_limit + iso_data->near_heap_limit_headroom;
+}
+
+// CountGC counts the garbage collections of the isolate, before each of them.
+static void CountGC(Isolate* iso,
+                    GCType type,
+                    GCCallbackFlags flags,
+                    void* data) {
+  static_cast<m_isolate_data*>(data)->gc_count++;
+}
+
+IsolatePtr NewIsolate(IsolateOptions options) {
+  m_isolate_data* iso_data = new m_isolate_data;
+  iso_data->near_heap_limit_headroom = options.near_heap_limit_headroom;
+
+  Isolate::CreateParams params;
+  params.array_buffer_allocator = default_allocator;
+  if (options.max_heap_size > 0) {
+    params.constraints.ConfigureDefaultsFromHeapSize(0, options.max_heap_size);
+  }
+  if (options.snapshot_blob != nullptr) {
+    iso_data->snapshot_data.assign(options.snapshot_blob,
+                                   options.snapshot_blob_length);
+    iso_data->snapshot_blob = {iso_data->snapshot_data.data(),
+                               options.snapshot_blob_length};
+    params.snapshot_blob = &iso_data->snapshot_blob;
+  }
+  Isolate* iso = Isolate::New(params);
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  HandleScope handle_scope(iso);
+
+  iso->SetCaptureStackTraceForUncaughtExceptions(true);
+  iso->SetHostImportModuleDynamicallyCallback(ImportModuleDynamically);
+  iso->SetHostInitializeImportMetaObjectCallback(InitializeImportMeta);
+
+  iso_data->iso = iso;
+  iso->SetData(1, iso_data);
+  iso->AddGCPrologueCallback(CountGC, iso_data);
+  if (options.near_heap_limit_headroom > 0) {
+    iso->AddNearHeapLimitCallback(TerminateNearHeapLimit, iso_data);
+  }
+
+  // Create a Context for internal use
+  m_ctx* ctx = new m_ctx;
+  ctx->ptr.Reset(iso, Context::New(iso));
+  ctx->iso = iso;
+  iso->SetData(0, ctx);
+
+  return iso;
+}
+
+void IsolatePerformMicrotaskCheckpoint(IsolatePtr iso) {
+  ISOLATE_SCOPE(iso)
+  iso->PerformMicrotaskCheckpoint();
+}
+
+void IsolateDispose(IsolatePtr iso) {
+  if (iso == nullptr) {
+    return;
+  }
+  auto ctx = static_cast<m_ctx*>(iso->GetData(0));
+  ContextFree(ctx);
+  auto iso_data = isolateData(iso);
+
+  iso->Dispose();
+  delete iso_data;
+}
+
+int IsolateNearHeapLimitReached(IsolatePtr iso) {
+  return isolateData(iso)->near_heap_limit_reached.load();
+}
+
+uint64_t IsolateGCCount(IsolatePtr iso) {
+  return isolateData(iso)->gc_count.load();
+}
+
+void IsolateTerminateExecution(IsolatePtr iso) {
+  iso->TerminateExecution();
+}
+
+int IsolateIsExecutionTerminating(IsolatePtr iso) {
+  return iso->IsExecutionTerminating();
+}
+
+IsolateHStatistics IsolationGetHeapStatistics(IsolatePtr iso) {
+  if (iso == nullptr) {
+    return IsolateHStatistics{0};
+  }
+  v8::HeapStatistics hs;
+  iso->GetHeapStatistics(&hs);
+
+  return IsolateHStatistics{hs.total_heap_size(),
+                            hs.total_heap_size_executable(),
+                            hs.total_physical_size(),
+                            hs.total_available_size(),
+                            hs.used_heap_size(),
+                            hs.heap_size_limit(),
+                            hs.malloced_memory(),
+                            hs.external_memory(),
+                            hs.peak_malloced_memory(),
+                            hs.number_of_native_contexts(),
+                            hs.number_of_detached_contexts()};
+}
+}
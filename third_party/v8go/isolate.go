@@ -0,0 +1,249 @@
+// Copyright 2019 Roger Chapman and the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include <stdlib.h>
+// #include "isolate.h"
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// Isolate is a JavaScript VM instance with its own heap and
+// garbage collector. Most applications will create one isolate
+// with many V8 contexts for execution.
+type Isolate struct {
+	ptr C.IsolatePtr
+
+	cbMutex sync.RWMutex
+	cbSeq   int
+	cbs     map[int]FunctionCallbackWithError
+
+	null      *Value
+	undefined *Value
+}
+
+// HeapStatistics represents V8 isolate heap statistics
+type HeapStatistics struct {
+	TotalHeapSize            uint64
+	TotalHeapSizeExecutable  uint64
+	TotalPhysicalSize        uint64
+	TotalAvailableSize       uint64
+	UsedHeapSize             uint64
+	HeapSizeLimit            uint64
+	MallocedMemory           uint64
+	ExternalMemory           uint64
+	PeakMallocedMemory       uint64
+	NumberOfNativeContexts   uint64
+	NumberOfDetachedContexts uint64
+}
+
+type isolateOptions struct {
+	maxHeapSize           uint64
+	nearHeapLimitHeadroom uint64
+	snapshotBlob          []byte
+}
+
+// IsolateOption sets options such as ResourceConstraints to the NewIsolate
+type IsolateOption interface {
+	applyIsolate(*isolateOptions)
+}
+
+// ResourceConstraints sets the create params of the heap of an isolate. Unlike the
+// --max-heap-size flag, it only applies to the isolate it is passed to.
+type ResourceConstraints struct {
+	// MaxHeapSize is the limit of the heap in bytes, 0 for the default of V8.
+	// V8 aborts the process once the heap exceeds it, unless NearHeapLimit is set.
+	MaxHeapSize uint64
+}
+
+func (o ResourceConstraints) applyIsolate(opts *isolateOptions) {
+	opts.maxHeapSize = o.MaxHeapSize
+}
+
+// NearHeapLimit installs a near heap limit callback, which terminates the execution
+// once the heap reaches its limit, and raises the limit by Headroom bytes so that the
+// execution can unwind. It does it once per isolate: V8 still aborts the process if the
+// heap reaches the raised limit. See Isolate.NearHeapLimitReached.
+type NearHeapLimit struct {
+	Headroom uint64
+}
+
+func (o NearHeapLimit) applyIsolate(opts *isolateOptions) {
+	opts.nearHeapLimitHeadroom = o.Headroom
+}
+
+// NewIsolate creates a new V8 isolate. Only one thread may access
+// a given isolate at a time, but different threads may access
+// different isolates simultaneously.
+// When an isolate is no longer used its resources should be freed
+// by calling iso.Dispose().
+// An *Isolate can be used as a v8go.ContextOption to create a new
+// Context, rather than creating a new default Isolate.
+func NewIsolate(opt ...IsolateOption) *Isolate {
+	initializeIfNecessary()
+	opts := isolateOptions{}
+	for _, o := range opt {
+		if o != nil {
+			o.applyIsolate(&opts)
+		}
+	}
+	cOptions := C.IsolateOptions{
+		max_heap_size:            C.size_t(opts.maxHeapSize),
+		near_heap_limit_headroom: C.size_t(opts.nearHeapLimitHeadroom),
+	}
+	if len(opts.snapshotBlob) > 0 {
+		// copied by NewIsolate
+		cOptions.snapshot_blob = (*C.char)(unsafe.Pointer(&opts.snapshotBlob[0]))
+		cOptions.snapshot_blob_length = C.int(len(opts.snapshotBlob))
+	}
+	iso := &Isolate{
+		ptr: C.NewIsolate(cOptions),
+		cbs: make(map[int]FunctionCallbackWithError),
+	}
+	iso.null = newValueNull(iso)
+	iso.undefined = newValueUndefined(iso)
+	return iso
+}
+
+// TerminateExecution terminates forcefully the current thread
+// of JavaScript execution in the given isolate.
+func (i *Isolate) TerminateExecution() {
+	C.IsolateTerminateExecution(i.ptr)
+}
+
+// NearHeapLimitReached reports whether the heap has reached its limit, and the
+// execution has been terminated by the callback installed by NearHeapLimit.
+// The isolate cannot raise its limit again, it should be disposed.
+func (i *Isolate) NearHeapLimitReached() bool {
+	return C.IsolateNearHeapLimitReached(i.ptr) == 1
+}
+
+// GCCount returns the number of garbage collections of the isolate so far, counted
+// by a GC prologue callback, minor and major collections alike.
+func (i *Isolate) GCCount() uint64 {
+	return uint64(C.IsolateGCCount(i.ptr))
+}
+
+// IsExecutionTerminating returns whether V8 is currently terminating
+// Javascript execution. If true, there are still JavaScript frames
+// on the stack and the termination exception is still active.
+func (i *Isolate) IsExecutionTerminating() bool {
+	return C.IsolateIsExecutionTerminating(i.ptr) == 1
+}
+
+type CompileOptions struct {
+	CachedData *CompilerCachedData
+
+	Mode CompileMode
+}
+
+// CompileUnboundScript will create an UnboundScript (i.e. context-indepdent)
+// using the provided source JavaScript, origin (a.k.a. filename), and options.
+// If options contain a non-null CachedData, compilation of the script will use
+// that code cache.
+// error will be of type `JSError` if not nil.
+func (i *Isolate) CompileUnboundScript(
+	source, origin string,
+	opts CompileOptions,
+) (*UnboundScript, error) {
+	cSource := C.CString(source)
+	cOrigin := C.CString(origin)
+	defer C.free(unsafe.Pointer(cSource))
+	defer C.free(unsafe.Pointer(cOrigin))
+
+	var cOptions C.CompileOptions
+	if opts.CachedData != nil {
+		if opts.Mode != 0 {
+			panic("On CompileOptions, Mode and CachedData can't both be set")
+		}
+		cOptions.compileOption = C.ScriptCompilerConsumeCodeCache
+		cOptions.cachedData = C.ScriptCompilerCachedData{
+			data:   (*C.uchar)(unsafe.Pointer(&opts.CachedData.Bytes[0])),
+			length: C.int(len(opts.CachedData.Bytes)),
+		}
+	} else {
+		cOptions.compileOption = C.int(opts.Mode)
+	}
+
+	rtn := C.IsolateCompileUnboundScript(i.ptr, cSource, cOrigin, cOptions)
+	if rtn.ptr == nil {
+		return nil, newJSError(rtn.error)
+	}
+	if opts.CachedData != nil {
+		opts.CachedData.Rejected = int(rtn.cachedDataRejected) == 1
+	}
+	return &UnboundScript{
+		ptr: rtn.ptr,
+		iso: i,
+	}, nil
+}
+
+// GetHeapStatistics returns heap statistics for an isolate.
+func (i *Isolate) GetHeapStatistics() HeapStatistics {
+	hs := C.IsolationGetHeapStatistics(i.ptr)
+
+	return HeapStatistics{
+		TotalHeapSize:            uint64(hs.total_heap_size),
+		TotalHeapSizeExecutable:  uint64(hs.total_heap_size_executable),
+		TotalPhysicalSize:        uint64(hs.total_physical_size),
+		TotalAvailableSize:       uint64(hs.total_available_size),
+		UsedHeapSize:             uint64(hs.used_heap_size),
+		HeapSizeLimit:            uint64(hs.heap_size_limit),
+		MallocedMemory:           uint64(hs.malloced_memory),
+		ExternalMemory:           uint64(hs.external_memory),
+		PeakMallocedMemory:       uint64(hs.peak_malloced_memory),
+		NumberOfNativeContexts:   uint64(hs.number_of_native_contexts),
+		NumberOfDetachedContexts: uint64(hs.number_of_detached_contexts),
+	}
+}
+
+// Dispose will dispose the Isolate VM; subsequent calls will panic.
+func (i *Isolate) Dispose() {
+	if i.ptr == nil {
+		return
+	}
+	C.IsolateDispose(i.ptr)
+	i.ptr = nil
+}
+
+// ThrowException schedules an exception to be thrown when returning to
+// JavaScript. When an exception has been scheduled it is illegal to invoke
+// any JavaScript operation; the caller must return immediately and only after
+// the exception has been handled does it become legal to invoke JavaScript operations.
+func (i *Isolate) ThrowException(value *Value) *Value {
+	if i.ptr == nil {
+		panic("Isolate has been disposed")
+	}
+	return &Value{
+		ptr: C.IsolateThrowException(i.ptr, value.ptr),
+	}
+}
+
+// Deprecated: use `iso.Dispose()`.
+func (i *Isolate) Close() {
+	i.Dispose()
+}
+
+func (i *Isolate) apply(opts *contextOptions) {
+	opts.iso = i
+}
+
+func (i *Isolate) registerCallback(cb FunctionCallbackWithError) int {
+	i.cbMutex.Lock()
+	i.cbSeq++
+	ref := i.cbSeq
+	i.cbs[ref] = cb
+	i.cbMutex.Unlock()
+	return ref
+}
+
+func (i *Isolate) getCallback(ref int) FunctionCallbackWithError {
+	i.cbMutex.RLock()
+	defer i.cbMutex.RUnlock()
+	return i.cbs[ref]
+}
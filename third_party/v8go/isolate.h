@@ -0,0 +1,91 @@
+#ifndef V8GO_ISOLATE_H
+#define V8GO_ISOLATE_H
+
+#include "unbound_script.h"
+
+#ifdef __cplusplus
+
+#include <atomic>
+#include <string>
+
+#include "deps/include/v8-snapshot.h"
+
+typedef v8::Isolate v8Isolate;
+
+// m_isolate_data is the state of an isolate kept by v8go, in the data slot 1.
+struct m_isolate_data {
+  v8::Isolate* iso;
+  size_t near_heap_limit_headroom;
+  std::atomic<bool> near_heap_limit_reached{false};
+  // gc_count counts the garbage collections of the isolate.
+  std::atomic<uint64_t> gc_count{0};
+  // snapshot_data is a copy of the startup snapshot of the isolate, which V8
+  // reads from again whenever a context is created.
+  std::string snapshot_data;
+  v8::StartupData snapshot_blob;
+};
+
+extern "C" {
+#else
+typedef struct v8Isolate v8Isolate;
+#endif
+
+typedef v8Isolate* IsolatePtr;
+
+typedef struct m_value m_value;
+typedef m_value* ValuePtr;
+
+// ScriptCompiler::CompileOptions values
+extern const int ScriptCompilerNoCompileOptions;
+extern const int ScriptCompilerConsumeCodeCache;
+extern const int ScriptCompilerEagerCompile;
+
+typedef struct {
+  ScriptCompilerCachedData cachedData;
+  int compileOption;
+} CompileOptions;
+
+typedef struct {
+  size_t total_heap_size;
+  size_t total_heap_size_executable;
+  size_t total_physical_size;
+  size_t total_available_size;
+  size_t used_heap_size;
+  size_t heap_size_limit;
+  size_t malloced_memory;
+  size_t external_memory;
+  size_t peak_malloced_memory;
+  size_t number_of_native_contexts;
+  size_t number_of_detached_contexts;
+} IsolateHStatistics;
+
+typedef struct {
+  // max_heap_size is the limit of the heap in bytes, 0 for the default of V8.
+  size_t max_heap_size;
+  // near_heap_limit_headroom installs the near heap limit callback if not 0.
+  size_t near_heap_limit_headroom;
+  // snapshot_blob boots the isolate from a startup snapshot if not NULL.
+  const char* snapshot_blob;
+  int snapshot_blob_length;
+} IsolateOptions;
+
+extern IsolatePtr NewIsolate(IsolateOptions options);
+extern int IsolateNearHeapLimitReached(IsolatePtr ptr);
+extern uint64_t IsolateGCCount(IsolatePtr ptr);
+extern void IsolatePerformMicrotaskCheckpoint(IsolatePtr ptr);
+extern void IsolateDispose(IsolatePtr ptr);
+extern void IsolateTerminateExecution(IsolatePtr ptr);
+extern int IsolateIsExecutionTerminating(IsolatePtr ptr);
+extern IsolateHStatistics IsolationGetHeapStatistics(IsolatePtr ptr);
+
+extern ValuePtr IsolateThrowException(IsolatePtr iso, ValuePtr value);
+
+extern RtnUnboundScript IsolateCompileUnboundScript(IsolatePtr iso_ptr,
+                                                    const char* source,
+                                                    const char* origin,
+                                                    CompileOptions options);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
+#endif
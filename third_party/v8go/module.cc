@@ -0,0 +1,222 @@
+#include "_cgo_export.h"
+
+#include "deps/include/v8-exception.h"
+#include "deps/include/v8-function.h"
+#include "deps/include/v8-promise.h"
+
+#include "context-macros.h"
+#include "module.h"
+#include "utils.h"
+#include "value.h"
+
+using namespace v8;
+
+// contextOf returns the v8go context of local_ctx, from the reference in its
+// embedder data, like FunctionTemplateCallback.
+static m_ctx* contextOf(Local<Context> local_ctx) {
+  int ctx_ref = local_ctx->GetEmbedderData(1).As<Integer>()->Value();
+  return goContext(ctx_ref);
+}
+
+// findModule returns the module of ctx that module is the handle of, if any.
+static m_module* findModule(m_ctx* ctx, Local<Module> module) {
+  for (m_module* m : ctx->modules) {
+    if (m->ptr == module) {
+      return m;
+    }
+  }
+  return nullptr;
+}
+
+// ResolveModule resolves the requests of a module to the modules they have
+// been linked to with ModuleSetResolved.
+static MaybeLocal<Module> ResolveModule(Local<Context> local_ctx,
+                                        Local<String> specifier,
+                                        Local<FixedArray> import_attributes,
+                                        Local<Module> referrer) {
+  Isolate* iso = local_ctx->GetIsolate();
+  String::Utf8Value spec(iso, specifier);
+  m_module* m = findModule(contextOf(local_ctx), referrer);
+  if (m != nullptr) {
+    auto it = m->resolved.find(*spec);
+    if (it != m->resolved.end()) {
+      return it->second->ptr.Get(iso);
+    }
+  }
+  std::string msg = std::string("Cannot resolve module ") + *spec;
+  iso->ThrowException(Exception::Error(
+      String::NewFromUtf8(iso, msg.c_str()).ToLocalChecked()));
+  return MaybeLocal<Module>();
+}
+
+// ReturnData returns the data of the function, i.e. the namespace of the
+// module a dynamic import resolves to.
+static void ReturnData(const FunctionCallbackInfo<Value>& info) {
+  info.GetReturnValue().Set(info.Data());
+}
+
+MaybeLocal<Promise> ImportModuleDynamically(
+    Local<Context> local_ctx,
+    Local<Data> host_defined_options,
+    Local<Value> resource_name,
+    Local<String> specifier,
+    Local<FixedArray> import_attributes) {
+  Isolate* iso = local_ctx->GetIsolate();
+  int ctx_ref = local_ctx->GetEmbedderData(1).As<Integer>()->Value();
+  String::Utf8Value referrer(iso, resource_name);
+  String::Utf8Value spec(iso, specifier);
+
+  goImportModule_return imported =
+      goImportModule(ctx_ref, *referrer, *spec);
+  if (imported.r1 != nullptr) {
+    Local<String> msg =
+        String::NewFromUtf8(iso, imported.r1).ToLocalChecked();
+    free(imported.r1);
+    Local<Promise::Resolver> resolver;
+    if (!Promise::Resolver::New(local_ctx).ToLocal(&resolver) ||
+        resolver->Reject(local_ctx, Exception::Error(msg)).IsNothing()) {
+      return MaybeLocal<Promise>();
+    }
+    return resolver->GetPromise();
+  }
+
+  // the module is instantiated by the importer, evaluating it again returns
+  // the promise of its first evaluation.
+  Local<Module> module = imported.r0->ptr.Get(iso);
+  Local<Value> evaluated;
+  if (!module->Evaluate(local_ctx).ToLocal(&evaluated)) {
+    return MaybeLocal<Promise>();
+  }
+  Local<Function> then;
+  if (!Function::New(local_ctx, ReturnData, module->GetModuleNamespace())
+           .ToLocal(&then)) {
+    return MaybeLocal<Promise>();
+  }
+  return evaluated.As<Promise>()->Then(local_ctx, then);
+}
+
+void InitializeImportMeta(Local<Context> local_ctx,
+                          Local<Module> module,
+                          Local<Object> meta) {
+  Isolate* iso = local_ctx->GetIsolate();
+  m_module* m = findModule(contextOf(local_ctx), module);
+  if (m == nullptr) {
+    return;
+  }
+  Local<String> url;
+  if (String::NewFromUtf8(iso, m->origin.c_str()).ToLocal(&url)) {
+    meta->CreateDataProperty(local_ctx, String::NewFromUtf8Literal(iso, "url"),
+                             url)
+        .FromMaybe(false);
+  }
+}
+
+RtnModule CompileModule(ContextPtr ctx, const char* source, const char* origin) {
+  LOCAL_CONTEXT(ctx);
+
+  RtnModule rtn = {};
+
+  MaybeLocal<String> maybeSrc =
+      String::NewFromUtf8(iso, source, NewStringType::kNormal);
+  MaybeLocal<String> maybeOgn =
+      String::NewFromUtf8(iso, origin, NewStringType::kNormal);
+  Local<String> src, ogn;
+  if (!maybeSrc.ToLocal(&src) || !maybeOgn.ToLocal(&ogn)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  ScriptOrigin script_origin(ogn, 0, 0, false, -1, Local<Value>(), false,
+                             false, true);
+  ScriptCompiler::Source script_source(src, script_origin);
+  Local<Module> module;
+  if (!ScriptCompiler::CompileModule(iso, &script_source).ToLocal(&module)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  m_module* m = new m_module;
+  m->ctx = ctx;
+  m->ptr.Reset(iso, module);
+  m->origin = origin;
+  ctx->modules.push_back(m);
+  rtn.ptr = m;
+  return rtn;
+}
+
+int ModuleGetRequestsLength(ModulePtr ptr) {
+  LOCAL_CONTEXT(ptr->ctx);
+  return ptr->ptr.Get(iso)->GetModuleRequests()->Length();
+}
+
+const char* ModuleGetRequest(ModulePtr ptr, int i) {
+  LOCAL_CONTEXT(ptr->ctx);
+  Local<FixedArray> requests = ptr->ptr.Get(iso)->GetModuleRequests();
+  Local<ModuleRequest> request = requests->Get(local_ctx, i).As<ModuleRequest>();
+  String::Utf8Value specifier(iso, request->GetSpecifier());
+  return CopyString(std::string(*specifier, specifier.length()));
+}
+
+void ModuleSetResolved(ModulePtr ptr, const char* specifier, ModulePtr target) {
+  ptr->resolved[specifier] = target;
+}
+
+RtnError ModuleInstantiate(ModulePtr ptr) {
+  LOCAL_CONTEXT(ptr->ctx);
+
+  RtnError rtn = {};
+  Local<Module> module = ptr->ptr.Get(iso);
+  if (module->InstantiateModule(local_ctx, ResolveModule).IsNothing()) {
+    rtn = ExceptionError(try_catch, iso, local_ctx);
+  }
+  return rtn;
+}
+
+RtnValue ModuleEvaluate(ModulePtr ptr) {
+  m_ctx* ctx = ptr->ctx;
+  LOCAL_CONTEXT(ctx);
+
+  RtnValue rtn = {};
+  Local<Module> module = ptr->ptr.Get(iso);
+  Local<Value> result;
+  if (!module->Evaluate(local_ctx).ToLocal(&result)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  // An error of the evaluation rejects its promise, which settles once the
+  // microtasks have run, unless the module awaits something else.
+  iso->PerformMicrotaskCheckpoint();
+  Local<Promise> promise = result.As<Promise>();
+  if (promise->State() == Promise::kRejected) {
+    // thrown again, so that it is reported like the errors of scripts.
+    iso->ThrowException(promise->Result());
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, promise);
+  rtn.value = tracked_value(ctx, val);
+  return rtn;
+}
+
+ValuePtr ModuleGetNamespace(ModulePtr ptr) {
+  m_ctx* ctx = ptr->ctx;
+  LOCAL_CONTEXT(ctx);
+
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, ptr->ptr.Get(iso)->GetModuleNamespace());
+  return tracked_value(ctx, val);
+}
+
+int ModuleGetStatus(ModulePtr ptr) {
+  LOCAL_CONTEXT(ptr->ctx);
+  return ptr->ptr.Get(iso)->GetStatus();
+}
@@ -0,0 +1,124 @@
+package v8go
+
+// #include <stdlib.h>
+// #include "module.h"
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// ModuleStatus is the status of a Module, see the Module::Status of V8.
+type ModuleStatus int
+
+const (
+	ModuleUninstantiated ModuleStatus = iota
+	ModuleInstantiating
+	ModuleInstantiated
+	ModuleEvaluating
+	ModuleEvaluated
+	ModuleErrored
+)
+
+// Module is an ES module compiled in a context. Its requests must be linked to
+// the modules they import with Link before it is instantiated.
+type Module struct {
+	ptr C.ModulePtr
+	ctx *Context
+}
+
+// ModuleImporter loads the module that specifier designates for a dynamic
+// import() of referrer, the origin of the importing module or script. The
+// module must be instantiated, it is evaluated by the import.
+type ModuleImporter func(referrer, specifier string) (*Module, error)
+
+// CompileModule compiles source as an ES module with origin as its name, which
+// is also the url of its import.meta. The module is freed with the context.
+// error will be of type `JSError` if not nil.
+func (c *Context) CompileModule(source, origin string) (*Module, error) {
+	cSource := C.CString(source)
+	cOrigin := C.CString(origin)
+	defer C.free(unsafe.Pointer(cSource))
+	defer C.free(unsafe.Pointer(cOrigin))
+
+	rtn := C.CompileModule(c.ptr, cSource, cOrigin)
+	if rtn.ptr == nil {
+		return nil, newJSError(rtn.error)
+	}
+	return &Module{ptr: rtn.ptr, ctx: c}, nil
+}
+
+// SetModuleImporter sets the importer of the dynamic imports of the context.
+// Without one, dynamic imports are rejected.
+func (c *Context) SetModuleImporter(importer ModuleImporter) {
+	c.importer = importer
+}
+
+// Requests returns the specifiers imported by the module, in source order.
+func (m *Module) Requests() []string {
+	n := int(C.ModuleGetRequestsLength(m.ptr))
+	requests := make([]string, n)
+	for i := range requests {
+		cSpecifier := C.ModuleGetRequest(m.ptr, C.int(i))
+		requests[i] = C.GoString(cSpecifier)
+		C.free(unsafe.Pointer(cSpecifier))
+	}
+	return requests
+}
+
+// Link links the request of the module for specifier to target.
+func (m *Module) Link(specifier string, target *Module) {
+	cSpecifier := C.CString(specifier)
+	defer C.free(unsafe.Pointer(cSpecifier))
+	C.ModuleSetResolved(m.ptr, cSpecifier, target.ptr)
+}
+
+// Instantiate instantiates the module and the modules it imports, which must
+// all be linked. Instantiating an instantiated module does nothing.
+// error will be of type `JSError` if not nil.
+func (m *Module) Instantiate() error {
+	rtn := C.ModuleInstantiate(m.ptr)
+	if rtn.msg != nil {
+		return newJSError(rtn)
+	}
+	return nil
+}
+
+// Evaluate evaluates the instantiated module and the modules it imports, then
+// runs the microtasks, so that its top-level awaits settle if they can. It
+// returns the promise of the evaluation, which is still pending if the module
+// awaits something else. Evaluating a module again returns the same promise.
+// error will be of type `JSError` if not nil, also if the promise is rejected.
+func (m *Module) Evaluate() (*Promise, error) {
+	rtn := C.ModuleEvaluate(m.ptr)
+	val, err := valueResult(m.ctx, rtn)
+	if err != nil {
+		return nil, err
+	}
+	return val.AsPromise()
+}
+
+// Namespace returns the namespace object of the instantiated module, whose
+// properties are the live bindings of its exports.
+func (m *Module) Namespace() *Object {
+	return &Object{&Value{C.ModuleGetNamespace(m.ptr), m.ctx}}
+}
+
+// Status returns the status of the module.
+func (m *Module) Status() ModuleStatus {
+	return ModuleStatus(C.ModuleGetStatus(m.ptr))
+}
+
+//export goImportModule
+func goImportModule(ctxref int, referrer, specifier *C.char) (C.ModulePtr, *C.char) {
+	ctx := getContext(ctxref)
+	name := C.GoString(specifier)
+	if ctx.importer == nil {
+		return nil, C.CString(fmt.Sprintf("Cannot import module %s", name))
+	}
+	m, err := ctx.importer(C.GoString(referrer), name)
+	if err != nil {
+		return nil, C.CString(err.Error())
+	}
+	return m.ptr, nil
+}
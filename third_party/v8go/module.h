@@ -0,0 +1,71 @@
+#ifndef V8GO_MODULE_H
+#define V8GO_MODULE_H
+
+#include "errors.h"
+
+#ifdef __cplusplus
+
+#include <string>
+#include <unordered_map>
+
+#include "deps/include/v8-callbacks.h"
+#include "deps/include/v8-persistent-handle.h"
+#include "deps/include/v8-script.h"
+
+typedef struct m_ctx m_ctx;
+
+// m_module is a module compiled in a context, which tracks it until it is
+// freed. resolved are the modules its requests are linked to, by specifier.
+struct m_module {
+  m_ctx* ctx;
+  v8::Persistent<v8::Module> ptr;
+  std::string origin;
+  std::unordered_map<std::string, m_module*> resolved;
+};
+
+// ImportModuleDynamically and InitializeImportMeta are the host callbacks of
+// import() and import.meta, installed on every isolate.
+extern v8::MaybeLocal<v8::Promise> ImportModuleDynamically(
+    v8::Local<v8::Context> context,
+    v8::Local<v8::Data> host_defined_options,
+    v8::Local<v8::Value> resource_name,
+    v8::Local<v8::String> specifier,
+    v8::Local<v8::FixedArray> import_attributes);
+extern void InitializeImportMeta(v8::Local<v8::Context> context,
+                                 v8::Local<v8::Module> module,
+                                 v8::Local<v8::Object> meta);
+
+extern "C" {
+#endif
+
+typedef struct m_ctx m_ctx;
+typedef m_ctx* ContextPtr;
+
+typedef struct m_module m_module;
+typedef m_module* ModulePtr;
+
+typedef struct m_value m_value;
+typedef m_value* ValuePtr;
+
+typedef struct {
+  ModulePtr ptr;
+  RtnError error;
+} RtnModule;
+
+extern RtnModule CompileModule(ContextPtr ctx_ptr,
+                               const char* source,
+                               const char* origin);
+extern int ModuleGetRequestsLength(ModulePtr ptr);
+extern const char* ModuleGetRequest(ModulePtr ptr, int i);
+extern void ModuleSetResolved(ModulePtr ptr,
+                              const char* specifier,
+                              ModulePtr target);
+extern RtnError ModuleInstantiate(ModulePtr ptr);
+extern RtnValue ModuleEvaluate(ModulePtr ptr);
+extern ValuePtr ModuleGetNamespace(ModulePtr ptr);
+extern int ModuleGetStatus(ModulePtr ptr);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
+#endif
@@ -0,0 +1,58 @@
+#include "deps/include/v8-context.h"
+#include "deps/include/v8-exception.h"
+#include "deps/include/v8-isolate.h"
+#include "deps/include/v8-primitive.h"
+#include "deps/include/v8-script.h"
+#include "deps/include/v8-snapshot.h"
+
+#include "snapshot.h"
+
+using namespace v8;
+
+extern ArrayBuffer::Allocator* default_allocator;
+
+RtnSnapshot CreateSnapshot(const char* source, const char* origin) {
+  RtnSnapshot rtn = {};
+  Isolate::CreateParams params;
+  params.array_buffer_allocator = default_allocator;
+  // the creator owns the isolate, and disposes of it when it is destroyed.
+  SnapshotCreator creator(params);
+  Isolate* iso = creator.GetIsolate();
+  {
+    Isolate::Scope isolate_scope(iso);
+    HandleScope handle_scope(iso);
+    Local<Context> local_ctx = Context::New(iso);
+    Context::Scope context_scope(local_ctx);
+    TryCatch try_catch(iso);
+
+    Local<String> src, ogn;
+    if (!String::NewFromUtf8(iso, source).ToLocal(&src) ||
+        !String::NewFromUtf8(iso, origin).ToLocal(&ogn)) {
+      rtn.error = ExceptionError(try_catch, iso, local_ctx);
+      return rtn;
+    }
+    ScriptOrigin script_origin(ogn);
+    Local<Script> script;
+    if (!Script::Compile(local_ctx, src, &script_origin).ToLocal(&script) ||
+        script->Run(local_ctx).IsEmpty()) {
+      rtn.error = ExceptionError(try_catch, iso, local_ctx);
+      return rtn;
+    }
+    creator.SetDefaultContext(local_ctx);
+  }
+  // the compiled functions are kept, so that they are not compiled again either.
+  StartupData blob =
+      creator.CreateBlob(SnapshotCreator::FunctionCodeHandling::kKeep);
+  rtn.data = blob.data;
+  rtn.length = blob.raw_size;
+  return rtn;
+}
+
+void SnapshotDataDelete(const char* data) {
+  delete[] data;
+}
+
+int SnapshotIsValid(const char* data, int length) {
+  StartupData blob = {data, length};
+  return blob.IsValid();
+}
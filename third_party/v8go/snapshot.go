@@ -0,0 +1,52 @@
+package v8go
+
+// #include <stdlib.h>
+// #include "snapshot.h"
+import "C"
+import (
+	"unsafe"
+)
+
+// CreateSnapshot runs source in a new context of a new isolate, and returns a startup
+// snapshot of the isolate, with that context as its default context. The contexts of an
+// isolate booted from the snapshot start with the state left by source, e.g. its globals,
+// without running it again. The compiled functions are kept in the snapshot.
+// error will be of type `JSError` if not nil.
+func CreateSnapshot(source, origin string) ([]byte, error) {
+	initializeIfNecessary()
+	cSource := C.CString(source)
+	cOrigin := C.CString(origin)
+	defer C.free(unsafe.Pointer(cSource))
+	defer C.free(unsafe.Pointer(cOrigin))
+
+	rtn := C.CreateSnapshot(cSource, cOrigin)
+	if rtn.data == nil {
+		return nil, newJSError(rtn.error)
+	}
+	defer C.SnapshotDataDelete(rtn.data)
+	return C.GoBytes(unsafe.Pointer(rtn.data), rtn.length), nil
+}
+
+// snapshotHeaderSize is the size of the header of a startup snapshot up to the end of
+// its version string, which V8 reads without checking the size of the snapshot.
+const snapshotHeaderSize = 16 + 64
+
+// ValidSnapshot reports whether blob is a startup snapshot that V8 can boot an isolate
+// from, i.e. created by the same version of V8, with the same flags.
+func ValidSnapshot(blob []byte) bool {
+	if len(blob) <= snapshotHeaderSize {
+		return false
+	}
+	initializeIfNecessary()
+	return C.SnapshotIsValid((*C.char)(unsafe.Pointer(&blob[0])), C.int(len(blob))) == 1
+}
+
+// StartupSnapshot boots an isolate from a snapshot created by CreateSnapshot. V8 aborts
+// the process if the snapshot is not valid, see ValidSnapshot.
+type StartupSnapshot struct {
+	Blob []byte
+}
+
+func (o StartupSnapshot) applyIsolate(opts *isolateOptions) {
+	opts.snapshotBlob = o.Blob
+}
@@ -0,0 +1,23 @@
+#ifndef V8GO_SNAPSHOT_H
+#define V8GO_SNAPSHOT_H
+
+#include "errors.h"
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct {
+  const char* data;
+  int length;
+  RtnError error;
+} RtnSnapshot;
+
+extern RtnSnapshot CreateSnapshot(const char* source, const char* origin);
+extern void SnapshotDataDelete(const char* data);
+extern int SnapshotIsValid(const char* data, int length);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
+#endif
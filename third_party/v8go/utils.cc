@@ -0,0 +1,19 @@
+#include "utils.h"
+#include <stdio.h>
+
+using namespace v8;
+
+const char* CopyString(std::string str) {
+  int len = str.length();
+  char* mem = (char*)malloc(len + 1);
+  memcpy(mem, str.data(), len);
+  mem[len] = 0;
+  return mem;
+}
+
+const char* CopyString(String::Utf8Value& value) {
+  if (value.length() == 0) {
+    return nullptr;
+  }
+  return CopyString(std::string(*value, value.length()));
+}
@@ -0,0 +1,573 @@
+#include "value.h"
+#include "context.h"
+#include "deps/include/v8-context.h"
+#include "isolate-macros.h"
+#include "utils.h"
+#include "value-macros.h"
+
+#define ISOLATE_SCOPE_INTERNAL_CONTEXT(iso) \
+  ISOLATE_SCOPE(iso);                       \
+  m_ctx* ctx = isolateInternalContext(iso);
+
+using namespace v8;
+
+void ValueRelease(ValuePtr ptr) {
+  if (ptr == nullptr) {
+    return;
+  }
+
+  ptr->ctx->vals.erase(ptr->id);
+  ptr->ptr.Reset();
+  delete ptr;
+}
+
+ValuePtr NewValueInteger(IsolatePtr iso, int32_t v) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, Integer::New(iso, v));
+  return tracked_value(ctx, val);
+}
+
+ValuePtr NewValueIntegerFromUnsigned(IsolatePtr iso, uint32_t v) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, Integer::NewFromUnsigned(iso, v));
+  return tracked_value(ctx, val);
+}
+
+RtnValue NewValueString(IsolatePtr iso, const char* v, int v_length) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  TryCatch try_catch(iso);
+  RtnValue rtn = {};
+  Local<String> str;
+  if (!String::NewFromUtf8(iso, v, NewStringType::kNormal, v_length)
+           .ToLocal(&str)) {
+    rtn.error = ExceptionError(try_catch, iso, ctx->ptr.Get(iso));
+    return rtn;
+  }
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, str);
+  rtn.value = tracked_value(ctx, val);
+  return rtn;
+}
+
+ValuePtr NewValueNull(IsolatePtr iso) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, Null(iso));
+  return tracked_value(ctx, val);
+}
+
+ValuePtr NewValueUndefined(IsolatePtr iso) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, Undefined(iso));
+  return tracked_value(ctx, val);
+}
+
+ValuePtr NewValueBoolean(IsolatePtr iso, int v) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, Boolean::New(iso, v));
+  return tracked_value(ctx, val);
+}
+
+ValuePtr NewValueNumber(IsolatePtr iso, double v) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, Number::New(iso, v));
+  return tracked_value(ctx, val);
+}
+
+ValuePtr NewValueBigInt(IsolatePtr iso, int64_t v) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, BigInt::New(iso, v));
+  return tracked_value(ctx, val);
+}
+
+ValuePtr NewValueBigIntFromUnsigned(IsolatePtr iso, uint64_t v) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, BigInt::NewFromUnsigned(iso, v));
+  return tracked_value(ctx, val);
+}
+
+RtnValue NewValueBigIntFromWords(IsolatePtr iso,
+                                 int sign_bit,
+                                 int word_count,
+                                 const uint64_t* words) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  TryCatch try_catch(iso);
+  Local<Context> local_ctx = ctx->ptr.Get(iso);
+
+  RtnValue rtn = {};
+  Local<BigInt> bigint;
+  if (!BigInt::NewFromWords(local_ctx, sign_bit, word_count, words)
+           .ToLocal(&bigint)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, bigint);
+  rtn.value = tracked_value(ctx, val);
+  return rtn;
+}
+
+ValuePtr NewValueError(IsolatePtr iso,
+                       ErrorTypeIndex idx,
+                       const char* message) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  Local<Context> local_ctx = ctx->ptr.Get(iso);
+  Context::Scope context_scope(local_ctx);
+
+  Local<String> local_msg = String::NewFromUtf8(iso, message).ToLocalChecked();
+  Local<Value> v;
+  switch (idx) {
+    case ERROR_RANGE:
+      v = Exception::RangeError(local_msg);
+      break;
+    case ERROR_REFERENCE:
+      v = Exception::ReferenceError(local_msg);
+      break;
+    case ERROR_SYNTAX:
+      v = Exception::SyntaxError(local_msg);
+      break;
+    case ERROR_TYPE:
+      v = Exception::TypeError(local_msg);
+      break;
+    case ERROR_WASM_COMPILE:
+      v = Exception::WasmCompileError(local_msg);
+      break;
+    case ERROR_WASM_LINK:
+      v = Exception::WasmLinkError(local_msg);
+      break;
+    case ERROR_WASM_RUNTIME:
+      v = Exception::WasmRuntimeError(local_msg);
+      break;
+    case ERROR_GENERIC:
+      v = Exception::Error(local_msg);
+      break;
+    default:
+      return nullptr;
+  }
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Global<Value>(iso, v);
+  return tracked_value(ctx, val);
+}
+
+const uint32_t* ValueToArrayIndex(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  Local<Uint32> array_index;
+  if (!value->ToArrayIndex(local_ctx).ToLocal(&array_index)) {
+    return nullptr;
+  }
+
+  uint32_t* idx = (uint32_t*)malloc(sizeof(uint32_t));
+  *idx = array_index->Value();
+  return idx;
+}
+
+int ValueToBoolean(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->BooleanValue(iso);
+}
+
+int32_t ValueToInt32(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->Int32Value(local_ctx).ToChecked();
+}
+
+int64_t ValueToInteger(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IntegerValue(local_ctx).ToChecked();
+}
+
+double ValueToNumber(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->NumberValue(local_ctx).ToChecked();
+}
+
+RtnString ValueToDetailString(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  RtnString rtn = {0};
+  Local<String> str;
+  if (!value->ToDetailString(local_ctx).ToLocal(&str)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+  String::Utf8Value ds(iso, str);
+  rtn.data = CopyString(ds);
+  rtn.length = ds.length();
+  return rtn;
+}
+
+RtnString ValueToString(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  RtnString rtn = {0};
+  // String::Utf8Value will result in an empty string if conversion to a string
+  // fails
+  // TODO: Consider propagating the JS error. A fallback value could be returned
+  // in Value.String()
+  String::Utf8Value src(iso, value);
+  char* data = static_cast<char*>(malloc(src.length()));
+  memcpy(data, *src, src.length());
+  rtn.data = data;
+  rtn.length = src.length();
+  return rtn;
+}
+
+uint32_t ValueToUint32(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->Uint32Value(local_ctx).ToChecked();
+}
+
+ValueBigInt ValueToBigInt(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  Local<BigInt> bint;
+  if (!value->ToBigInt(local_ctx).ToLocal(&bint)) {
+    return {nullptr, 0};
+  }
+
+  int word_count = bint->WordCount();
+  int sign_bit = 0;
+  uint64_t* words = (uint64_t*)malloc(sizeof(uint64_t) * word_count);
+  bint->ToWordsArray(&sign_bit, &word_count, words);
+  ValueBigInt rtn = {words, word_count, sign_bit};
+  return rtn;
+}
+
+RtnValue ValueToObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  RtnValue rtn = {};
+  Local<Object> obj;
+  if (!value->ToObject(local_ctx).ToLocal(&obj)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+  m_value* new_val = new m_value;
+  new_val->id = 0;
+  new_val->iso = iso;
+  new_val->ctx = ctx;
+  new_val->ptr = Global<Value>(iso, obj);
+  rtn.value = tracked_value(ctx, new_val);
+  return rtn;
+}
+
+int ValueSameValue(ValuePtr val1, ValuePtr val2) {
+  Isolate* iso = val1->iso;
+  ISOLATE_SCOPE(iso);
+  Local<Value> value1 = val1->ptr.Get(iso);
+  Local<Value> value2 = val2->ptr.Get(iso);
+
+  return value1->SameValue(value2);
+}
+
+int ValueIsUndefined(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsUndefined();
+}
+
+int ValueIsNull(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsNull();
+}
+
+int ValueIsNullOrUndefined(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsNullOrUndefined();
+}
+
+int ValueIsTrue(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsTrue();
+}
+
+int ValueIsFalse(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsFalse();
+}
+
+int ValueIsName(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsName();
+}
+
+int ValueIsString(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsString();
+}
+
+int ValueIsSymbol(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsSymbol();
+}
+
+int ValueIsFunction(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsFunction();
+}
+
+int ValueIsObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsObject();
+}
+
+int ValueIsBigInt(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsBigInt();
+}
+
+int ValueIsBoolean(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsBoolean();
+}
+
+int ValueIsNumber(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsNumber();
+}
+
+int ValueIsExternal(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsExternal();
+}
+
+int ValueIsInt32(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsInt32();
+}
+
+int ValueIsUint32(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsUint32();
+}
+
+int ValueIsDate(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsDate();
+}
+
+int ValueIsArgumentsObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsArgumentsObject();
+}
+
+int ValueIsBigIntObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsBigIntObject();
+}
+
+int ValueIsNumberObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsNumberObject();
+}
+
+int ValueIsStringObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsStringObject();
+}
+
+int ValueIsSymbolObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsSymbolObject();
+}
+
+int ValueIsNativeError(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsNativeError();
+}
+
+int ValueIsRegExp(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsRegExp();
+}
+
+int ValueIsAsyncFunction(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsAsyncFunction();
+}
+
+int ValueIsGeneratorFunction(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsGeneratorFunction();
+}
+
+int ValueIsGeneratorObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsGeneratorObject();
+}
+
+int ValueIsPromise(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsPromise();
+}
+
+int ValueIsMap(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsMap();
+}
+
+int ValueIsSet(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsSet();
+}
+
+int ValueIsMapIterator(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsMapIterator();
+}
+
+int ValueIsSetIterator(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsSetIterator();
+}
+
+int ValueIsWeakMap(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsWeakMap();
+}
+
+int ValueIsWeakSet(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsWeakSet();
+}
+
+int ValueIsArray(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsArray();
+}
+
+int ValueIsArrayBuffer(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsArrayBuffer();
+}
+
+int ValueIsArrayBufferView(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsArrayBufferView();
+}
+
+int ValueIsTypedArray(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsTypedArray();
+}
+
+int ValueIsUint8Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsUint8Array();
+}
+
+int ValueIsUint8ClampedArray(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsUint8ClampedArray();
+}
+
+int ValueIsInt8Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsInt8Array();
+}
+
+int ValueIsUint16Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsUint16Array();
+}
+
+int ValueIsInt16Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsInt16Array();
+}
+
+int ValueIsUint32Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsUint32Array();
+}
+
+int ValueIsInt32Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsInt32Array();
+}
+
+int ValueIsFloat32Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsFloat32Array();
+}
+
+int ValueIsFloat64Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsFloat64Array();
+}
+
+int ValueIsBigInt64Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsBigInt64Array();
+}
+
+int ValueIsBigUint64Array(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsBigUint64Array();
+}
+
+int ValueIsDataView(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsDataView();
+}
+
+int ValueIsSharedArrayBuffer(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsSharedArrayBuffer();
+}
+
+int ValueIsProxy(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsProxy();
+}
+
+int ValueIsWasmModuleObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsWasmModuleObject();
+}
+
+int ValueIsModuleNamespaceObject(ValuePtr ptr) {
+  LOCAL_VALUE(ptr);
+  return value->IsModuleNamespaceObject();
+}
+
+int ValueStrictEquals(ValuePtr ptr, ValuePtr otherPtr) {
+  LOCAL_VALUE(ptr);
+  Local<Value> other = otherPtr->ptr.Get(iso);
+  return value->StrictEquals(other);
+}